@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// appOnlyAnnotation marks the commands that only read the public catalog,
+// so they can run without a user through the client credentials flow.
+const appOnlyAnnotation = "spotifycli/app-only"
+
+var (
+	// appOnly is the --app-only flag of the catalog commands, also set when
+	// they run without a user by default.
+	appOnly bool
+)
+
+// catalogCommand returns the annotations of a command that only reads the
+// public catalog.
+func catalogCommand() map[string]string {
+	return map[string]string{appOnlyAnnotation: "true"}
+}
+
+// allowsAppOnly reports whether cmd can run without a user.
+func allowsAppOnly(cmd *cobra.Command) bool {
+	return cmd.Annotations[appOnlyAnnotation] == "true"
+}
+
+// useAppOnly reports whether cmd runs without a user: when asked to with
+// --app-only, or when nobody logged in and the client secret is known.
+func useAppOnly(cmd *cobra.Command, config *oauth2.Config, store *tokenStore) (bool, error) {
+	if !allowsAppOnly(cmd) {
+		return false, nil
+	}
+	if appOnly {
+		if config.ClientSecret == "" {
+			return false, errors.New("--app-only needs the client secret of the application")
+		}
+		return true, nil
+	}
+	if config.ClientSecret == "" {
+		return false, nil
+	}
+	_, err := os.Stat(store.path)
+	return os.IsNotExist(err), nil
+}
+
+// newAppOnlyClient returns an HTTP client authenticated as the application
+// of config alone.
+func newAppOnlyClient(config *oauth2.Config) *http.Client {
+	cc := &clientcredentials.Config{
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		TokenURL:     config.Endpoint.TokenURL,
+	}
+	return cc.Client(oauthContext())
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestUseAppOnly(t *testing.T) {
+	root := NewRootCmd()
+	search := findCommand(t, root, "search")
+	list := findCommand(t, root, "list")
+
+	loggedOut := &tokenStore{path: t.TempDir() + "/" + tokenFile}
+	loggedIn := &tokenStore{path: t.TempDir() + "/" + tokenFile}
+	if err := loggedIn.Save(&oauth2.Token{AccessToken: "access"}); err != nil {
+		t.Fatal(err)
+	}
+	withSecret := &oauth2.Config{ClientID: "id", ClientSecret: "secret"}
+	withoutSecret := &oauth2.Config{ClientID: "id"}
+
+	tests := []struct {
+		name    string
+		cmd     string
+		flag    bool
+		config  *oauth2.Config
+		store   *tokenStore
+		want    bool
+		wantErr bool
+	}{
+		{"not logged in", "search", false, withSecret, loggedOut, true, false},
+		{"logged in", "search", false, withSecret, loggedIn, false, false},
+		{"flag", "search", true, withSecret, loggedIn, true, false},
+		{"no secret", "search", false, withoutSecret, loggedOut, false, false},
+		{"flag without secret", "search", true, withoutSecret, loggedOut, false, true},
+		{"user command", "list", false, withSecret, loggedOut, false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := search
+			if test.cmd == "list" {
+				cmd = list
+			}
+			appOnly = test.flag
+			defer func() { appOnly = false }()
+
+			got, err := useAppOnly(cmd, test.config, test.store)
+			if test.wantErr != (err != nil) {
+				t.Fatalf("got error %v, want error: %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAppOnlyClient(t *testing.T) {
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "id" || secret != "secret" {
+			t.Errorf("got client credentials %q:%q", id, secret)
+		}
+		if grant := r.PostFormValue("grant_type"); grant != "client_credentials" {
+			t.Errorf("got grant type %q, want client_credentials", grant)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"app","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer tokenSrv.Close()
+
+	var auth string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"t100000000000000000000","name":"Numb"}`)
+	}))
+	defer api.Close()
+
+	httpClient = tokenSrv.Client()
+	defer func() { httpClient = nil }()
+	config := &oauth2.Config{ClientID: "id", ClientSecret: "secret", Endpoint: oauth2.Endpoint{TokenURL: tokenSrv.URL}}
+
+	c := newWebAPIClient(newAppOnlyClient(config))
+	c.baseURL = api.URL + "/v1/"
+	if _, err := c.GetTrack("t100000000000000000000"); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer app" {
+		t.Errorf("got Authorization %q, want the app token", auth)
+	}
+}
+
+func TestShowAppOnly(t *testing.T) {
+	srv := newTestServer(t)
+	defer func() { appOnly = false }()
+
+	// there is no user to show
+	out, err := execute(t, srv, "show", "--tid", "t100000000000000000000", "--app-only")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "User:") || !strings.Contains(out, "Numb") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
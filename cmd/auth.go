@@ -1,23 +1,49 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"html/template"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/agext/uuid"
 	"github.com/spf13/cobra"
-	"github.com/zmb3/spotify"
 	"golang.org/x/oauth2"
 )
 
 var (
-	ch = make(chan *oauth2.Token)
+	// login flags
+	loginPKCE        bool
+	loginNoBrowser   bool
+	loginRedirectURI string
+	loginTimeout     time.Duration
+
+	// stdin is read for the redirect URL in the --no-browser mode.
+	stdin io.Reader = os.Stdin
 )
 
 type authenticationHandler struct {
-	auth  spotify.Authenticator
-	state string
+	config *oauth2.Config
+	state  string
+	// verifier is the PKCE code verifier, empty when the client secret is
+	// used.
+	verifier string
+	// result receives the outcome of the first callback.
+	result chan callbackResult
+}
+
+type callbackResult struct {
+	token *oauth2.Token
+	err   error
 }
 
 func newLoginCmd() *cobra.Command {
@@ -28,50 +54,260 @@ func newLoginCmd() *cobra.Command {
 			return authorize(cmd, args)
 		},
 	}
+	loginCmd.Flags().BoolVar(&loginPKCE, "pkce", false, "Use the Authorization Code with PKCE flow, which needs no client secret.")
+	loginCmd.Flags().BoolVar(&loginNoBrowser, "no-browser", false, "Print the login URL and read the redirect URL from stdin instead of waiting for the callback, e.g. over SSH.")
+	loginCmd.Flags().StringVar(&loginRedirectURI, "redirect-uri", "", "Redirect URI registered for the application, the callback server listens on its host and port (default "+redirectURI+").")
+	loginCmd.Flags().DurationVar(&loginTimeout, "timeout", 5*time.Minute, "Give up waiting for the login after this long.")
 	return loginCmd
 }
 
+func newAuthCmd() *cobra.Command {
+	authCmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Inspect authentication",
+	}
+	authCmd.AddCommand(newAuthStatusCmd())
+	return authCmd
+}
+
+func newAuthStatusCmd() *cobra.Command {
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the logged in user, token and granted scopes, exit non-zero when not logged in",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return authStatus(cmd, args)
+		},
+	}
+	return statusCmd
+}
+
+func newWhoamiCmd() *cobra.Command {
+	whoamiCmd := &cobra.Command{
+		Use:   "whoami",
+		Short: "Show the logged in user, same as auth status",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return authStatus(cmd, args)
+		},
+	}
+	return whoamiCmd
+}
+
 func newLogoutCmd() *cobra.Command {
 	logoutCmd := &cobra.Command{
 		Use:   "logout",
 		Short: "Logout from Spotify account",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return deleteToken()
+			return tokens.Delete()
 		},
 	}
 	return logoutCmd
 }
 
+func authStatus(cmd *cobra.Command, args []string) error {
+	if _, err := tokens.Load(); err != nil {
+		printInfof("Not logged in with profile \"%s\".\n", activeProfile)
+		if os.IsNotExist(err) {
+			return errors.New("not logged in")
+		}
+		return err
+	}
+
+	// fetching the user refreshes an expired token, read it afterwards
+	user, err := client.CurrentUser()
+	if err != nil {
+		return fmt.Errorf("not authenticated: %v", err)
+	}
+	token, err := tokens.Load()
+	if err != nil {
+		return err
+	}
+
+	record := authStatusRecord{
+		Profile:      activeProfile,
+		TokenFile:    tokens.path,
+		User:         user.DisplayName,
+		UserID:       user.ID,
+		Expired:      !token.Expiry.IsZero() && token.Expiry.Before(time.Now()),
+		RefreshToken: token.RefreshToken != "",
+		Scopes:       grantedScopes(token),
+	}
+	if !token.Expiry.IsZero() {
+		record.Expiry = token.Expiry.Format(time.RFC3339)
+	}
+	return render(authStatusView, []authStatusRecord{record})
+}
+
 func authorize(cmd *cobra.Command, args []string) error {
+	if loginRedirectURI != "" {
+		oauthConfig.RedirectURL = loginRedirectURI
+	}
+
 	// use uuid as state
 	state := string(uuid.New().Hex())
+	handler := &authenticationHandler{config: oauthConfig, state: state, result: make(chan callbackResult, 1)}
 
-	// setup server for callback
-	http.Handle("/callback", &authenticationHandler{auth: auth, state: state})
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		log.Println("Got request for: ", r.URL.String())
-	})
-	go http.ListenAndServe(":8080", nil)
+	// without a client secret, PKCE is the only way to log in
+	authURL := oauthConfig.AuthCodeURL(state)
+	if loginPKCE || oauthConfig.ClientSecret == "" {
+		verifier, err := newPKCEVerifier()
+		if err != nil {
+			return err
+		}
+		handler.verifier = verifier
+		authURL = pkceAuthCodeURL(oauthConfig, state, verifier)
+	}
+
+	// the browser may not reach our localhost, let the user paste the redirect
+	if loginNoBrowser {
+		return authorizeFromInput(handler, authURL)
+	}
+
+	// setup server for callback, listening where the redirect URI points
+	redirect, err := url.Parse(oauthConfig.RedirectURL)
+	if err != nil {
+		return fmt.Errorf("invalid redirect URI: %v", err)
+	}
+	if redirect.Scheme != "http" {
+		return fmt.Errorf("redirect URI %s must use http to be served locally", oauthConfig.RedirectURL)
+	}
+	addr := redirect.Host
+	if redirect.Port() == "" {
+		addr += ":80"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("cannot listen for the login callback: %v", err)
+	}
+	path := redirect.Path
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
 
 	// User authentication process
-	fmt.Println("authorize")
-	url := auth.AuthURL(state)
-	fmt.Println("Please log in to Spotify by visiting the following page in your browser:", url)
+	printInfo("Please log in to Spotify by visiting the following page in your browser:", authURL)
 
-	// persist token
-	token := <-ch
-	return persistToken(token)
+	// wait for the callback, the timeout or Ctrl-C
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	select {
+	case res := <-handler.result:
+		if res.err != nil {
+			return res.err
+		}
+		// persist token
+		return tokens.Save(res.token)
+	case <-time.After(loginTimeout):
+		return fmt.Errorf("login timed out after %v", loginTimeout)
+	case <-interrupt:
+		return errors.New("login canceled")
+	}
 }
 
 func (handler *authenticationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	token, err := handler.auth.Token(handler.state, r)
+	token, err := handler.token(r)
+	page := loginPage{Success: err == nil}
+	if err != nil {
+		page.Error = err.Error()
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusForbidden)
+	}
+	loginPageTemplate.Execute(w, page)
+
+	// only the first callback counts
+	select {
+	case handler.result <- callbackResult{token: token, err: err}:
+	default:
+	}
+}
+
+type loginPage struct {
+	Success bool
+	Error   string
+}
+
+var loginPageTemplate = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head><title>spotifycli</title></head>
+<body>
+{{if .Success}}<h1>Logged in</h1>
+<p>You can close this window and return to the terminal.</p>
+{{else}}<h1>Login failed</h1>
+<p>{{.Error}}</p>
+{{end}}</body>
+</html>
+`))
+
+// authorizeFromInput has the user open authURL in any browser and paste the
+// URL they are redirected to on stdin.
+func authorizeFromInput(handler *authenticationHandler, authURL string) error {
+	printInfo("Please log in to Spotify by visiting the following page in any browser:", authURL)
+	printInfo("The browser is then redirected to a page that may fail to load. Paste its URL here:")
+
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return errors.New("no redirect URL given")
+	}
+	values, err := redirectValues(strings.TrimSpace(line))
 	if err != nil {
-		http.Error(w, "Couldn't get token", http.StatusForbidden)
-		log.Fatal(err)
+		return err
+	}
+
+	// persist token
+	token, err := handler.exchange(values)
+	if err != nil {
+		return err
+	}
+	return tokens.Save(token)
+}
+
+// redirectValues returns the query parameters of the pasted redirect URL. A
+// bare code is refused: without the state next to it, there is no telling
+// it comes from the login just started.
+func redirectValues(input string) (url.Values, error) {
+	if input == "" {
+		return nil, errors.New("no redirect URL given")
+	}
+	if !strings.Contains(input, "?") {
+		return nil, errors.New("paste the whole URL you were redirected to, not only the code in it")
+	}
+	u, err := url.Parse(input)
+	if err != nil {
+		return nil, err
+	}
+	return u.Query(), nil
+}
+
+// token validates the callback request and exchanges its code for a token.
+func (handler *authenticationHandler) token(r *http.Request) (*oauth2.Token, error) {
+	return handler.exchange(r.URL.Query())
+}
+
+// exchange validates the parameters of the redirect and exchanges its code
+// for a token.
+func (handler *authenticationHandler) exchange(values url.Values) (*oauth2.Token, error) {
+	if e := values.Get("error"); e != "" {
+		return nil, errors.New("spotify: auth failed - " + e)
+	}
+	code := values.Get("code")
+	if code == "" {
+		return nil, errors.New("spotify: didn't get access code")
+	}
+	if values.Get("state") != handler.state {
+		return nil, errors.New("spotify: redirect state parameter doesn't match")
 	}
-	if st := r.FormValue("state"); st != handler.state {
-		http.NotFound(w, r)
-		log.Fatalf("state miss match: %s != %s\n", st, handler.state)
+	if handler.verifier != "" {
+		return pkceExchange(handler.config, code, handler.verifier)
 	}
-	ch <- token
+	return handler.config.Exchange(oauthContext(), code)
 }
@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// setupLogin points login at a fake token endpoint accepting the code
+// "code", redirects to redirect and writes the output to out.
+func setupLogin(t *testing.T, redirect string, out io.Writer) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if code := r.PostFormValue("code"); code != "code" {
+			http.Error(w, "invalid code "+code, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"access","token_type":"Bearer","refresh_token":"refresh","expires_in":3600}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	stdout, stderr, httpClient = out, out, srv.Client()
+	oauthConfig = &oauth2.Config{
+		ClientID:    "id",
+		RedirectURL: redirect,
+		Endpoint:    oauth2.Endpoint{AuthURL: "https://accounts.example.com/authorize", TokenURL: srv.URL},
+	}
+	tokens = &tokenStore{path: t.TempDir() + "/" + tokenFile}
+	loginTimeout = time.Minute
+	t.Cleanup(func() {
+		stdout, stderr, stdin, httpClient = origStdout, origStderr, origStdin, nil
+	})
+}
+
+// authState returns the state in the auth URL found in out.
+func authState(t *testing.T, out string) string {
+	for _, field := range strings.Fields(out) {
+		if strings.HasPrefix(field, "https://") {
+			u, err := url.Parse(field)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return u.Query().Get("state")
+		}
+	}
+	t.Fatalf("no auth URL in %q", out)
+	return ""
+}
+
+// loginWithInput runs login --no-browser, pasting the redirect built by
+// redirect from the state in the printed auth URL.
+func loginWithInput(t *testing.T, redirect func(state string) string) (*tokenStore, error) {
+	// the input is built once login reads it, after printing the auth URL
+	var out bytes.Buffer
+	setupLogin(t, redirectURI, &out)
+	stdin = &lazyReader{input: func() string {
+		return redirect(authState(t, out.String())) + "\n"
+	}}
+	loginNoBrowser = true
+	defer func() { loginNoBrowser = false }()
+	return tokens, authorize(nil, nil)
+}
+
+// lazyReader reads what input returns on the first read.
+type lazyReader struct {
+	input func() string
+	r     io.Reader
+}
+
+func (l *lazyReader) Read(p []byte) (int, error) {
+	if l.r == nil {
+		l.r = strings.NewReader(l.input())
+	}
+	return l.r.Read(p)
+}
+
+func TestLoginNoBrowser(t *testing.T) {
+	tests := []struct {
+		name     string
+		redirect func(state string) string
+		wantErr  bool
+	}{
+		{"url", func(state string) string {
+			return redirectURI + "?code=code&state=" + state
+		}, false},
+		{"bare code", func(state string) string { return "code" }, true},
+		{"no state", func(state string) string { return redirectURI + "?code=code" }, true},
+		{"state mismatch", func(state string) string { return redirectURI + "?code=code&state=other" }, true},
+		{"denied", func(state string) string { return redirectURI + "?error=access_denied" }, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, err := loginWithInput(t, test.redirect)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.AccessToken != "access" {
+				t.Errorf("got access token %q, want access", token.AccessToken)
+			}
+		})
+	}
+}
+
+// freeRedirectURI returns a redirect URI on a free local port.
+func freeRedirectURI(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return "http://" + l.Addr().String() + "/callback"
+}
+
+func TestLoginCallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      func(state string) string
+		wantStatus int
+		wantPage   string
+		wantErr    bool
+	}{
+		{"success", func(state string) string { return "code=code&state=" + state }, http.StatusOK, "Logged in", false},
+		{"state mismatch", func(state string) string { return "code=code&state=other" }, http.StatusForbidden, "state parameter doesn&#39;t match", true},
+		{"denied", func(state string) string { return "error=access_denied&state=" + state }, http.StatusForbidden, "access_denied", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			redirect := freeRedirectURI(t)
+			pr, pw := io.Pipe()
+			setupLogin(t, redirect, pw)
+
+			done := make(chan error, 1)
+			go func() { done <- authorize(nil, nil) }()
+
+			// the auth URL is printed once the server listens
+			line, err := bufio.NewReader(pr).ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.Get(redirect + "?" + test.query(authState(t, line)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != test.wantStatus {
+				t.Errorf("got status %d, want %d", resp.StatusCode, test.wantStatus)
+			}
+			if !strings.Contains(string(body), test.wantPage) {
+				t.Errorf("page does not contain %q:\n%s", test.wantPage, body)
+			}
+
+			err = <-done
+			if test.wantErr != (err != nil) {
+				t.Fatalf("got error %v, want error: %v", err, test.wantErr)
+			}
+			if err == nil {
+				if _, err := tokens.Load(); err != nil {
+					t.Error(err)
+				}
+			}
+
+			// the server is shut down
+			if _, err := http.Get(redirect); err == nil {
+				t.Error("callback server still running after login")
+			}
+		})
+	}
+}
+
+func TestLoginTimeout(t *testing.T) {
+	setupLogin(t, freeRedirectURI(t), ioutil.Discard)
+	loginTimeout = 10 * time.Millisecond
+
+	err := authorize(nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("got error %v, want a timeout", err)
+	}
+}
+
+func TestAuthStatus(t *testing.T) {
+	srv := newTestServer(t)
+	tokens = &tokenStore{path: t.TempDir() + "/" + tokenFile}
+	activeProfile = defaultProfile
+
+	if _, err := execute(t, srv, "auth", "status"); err == nil {
+		t.Error("expected an error when not logged in")
+	}
+
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	token := withScopes(&oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: expiry}, []string{"user-read-private", "playlist-modify-public"})
+	if err := tokens.Save(token); err != nil {
+		t.Fatal(err)
+	}
+	out, err := execute(t, srv, "whoami", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []authStatusRecord
+	if err := json.Unmarshal([]byte(out), &records); err != nil {
+		t.Fatal(err)
+	}
+	want := authStatusRecord{
+		Profile:      defaultProfile,
+		TokenFile:    tokens.path,
+		User:         "Alice",
+		UserID:       "alice",
+		Expiry:       expiry.Format(time.RFC3339),
+		RefreshToken: true,
+		Scopes:       []string{"user-read-private", "playlist-modify-public"},
+	}
+	if len(records) != 1 || !reflect.DeepEqual(records[0], want) {
+		t.Errorf("got %+v, want %+v", records, want)
+	}
+}
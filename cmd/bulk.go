@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/zmb3/spotify"
+)
+
+const (
+	// addTracksLimit is the most tracks added to a playlist per request.
+	addTracksLimit = 100
+	// getTracksLimit is the most tracks fetched per request.
+	getTracksLimit = 50
+)
+
+// line is a line of an input file with its number, for reporting.
+type line struct {
+	number int
+	text   string
+}
+
+// readLines reads the non-blank lines of path, or of stdin when path is
+// "-". Lines starting with # are comments.
+func readLines(path string) ([]line, error) {
+	var r io.Reader = stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var lines []line
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		lines = append(lines, line{number: n, text: text})
+	}
+	return lines, scanner.Err()
+}
+
+// lookupTracks returns the catalog tracks of ids, in order, nil for those
+// that don't exist.
+func lookupTracks(ids []spotify.ID) ([]*spotify.FullTrack, error) {
+	var tracks []*spotify.FullTrack
+	for start := 0; start < len(ids); start += getTracksLimit {
+		end := start + getTracksLimit
+		if end > len(ids) {
+			end = len(ids)
+		}
+		page, err := client.GetTracks(ids[start:end]...)
+		if err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, page...)
+	}
+	return tracks, nil
+}
+
+// addTracksInChunks adds tracks to a playlist, addTracksLimit at a time. It
+// returns the number of tracks added and reports the tracks of failed
+// requests through failed.
+func addTracksInChunks(userID string, playlistID spotify.ID, tracks []*spotify.FullTrack, failed func(t *spotify.FullTrack, err error)) int {
+	added := 0
+	for start := 0; start < len(tracks); start += addTracksLimit {
+		end := start + addTracksLimit
+		if end > len(tracks) {
+			end = len(tracks)
+		}
+		chunk := tracks[start:end]
+		ids := make([]spotify.ID, len(chunk))
+		for i, t := range chunk {
+			ids[i] = t.ID
+		}
+		if _, err := client.AddTracksToPlaylist(userID, playlistID, ids...); err != nil {
+			for _, t := range chunk {
+				failed(t, err)
+			}
+			continue
+		}
+		added += len(chunk)
+	}
+	return added
+}
+
+// addTrackIDsFromFile adds the tracks listed by ID, URI or URL in path to
+// the playlist.
+func addTrackIDsFromFile(user *spotify.PrivateUser, pl spotify.SimplePlaylist, path string) error {
+	lines, err := readLines(path)
+	if err != nil {
+		return err
+	}
+
+	// parse every line first, so a typo doesn't fail a whole chunk
+	failures := 0
+	fail := func(l line, err error) {
+		failures++
+		fmt.Fprintf(stderr, "line %d: %s: %v\n", l.number, l.text, err)
+	}
+	var ids []spotify.ID
+	var parsed []line
+	for _, l := range lines {
+		id, err := resolveTrackID(l.text)
+		if err != nil {
+			fail(l, err)
+			continue
+		}
+		ids = append(ids, id)
+		parsed = append(parsed, l)
+	}
+
+	// check the tracks exist
+	found, err := lookupTracks(ids)
+	if err != nil {
+		return err
+	}
+	var tracks []*spotify.FullTrack
+	lineOf := make(map[*spotify.FullTrack]line)
+	for i, t := range found {
+		if t == nil {
+			fail(parsed[i], fmt.Errorf("track not found"))
+			continue
+		}
+		tracks = append(tracks, t)
+		lineOf[t] = parsed[i]
+	}
+
+	// add the ones not in the playlist yet
+	if tracks, err = skipPresent(pl, newPlaylistTrackIDs(user.ID, pl.ID), tracks); err != nil {
+		return err
+	}
+	added := addTracksInChunks(user.ID, pl.ID, tracks, func(t *spotify.FullTrack, err error) {
+		fail(lineOf[t], err)
+	})
+	printInfof("Added %d of %d tracks to playlist \"%s\".\n", added, len(lines), pl.Name)
+	if failures > 0 {
+		return fmt.Errorf("%d tracks could not be added", failures)
+	}
+	return nil
+}
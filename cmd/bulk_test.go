@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zmb3/spotify"
+)
+
+func TestAddTrackIDsFromFile(t *testing.T) {
+	srv := newTestServer(t)
+	id := srv.AddPlaylist(spotify.SimplePlaylist{Name: "Bulk"})
+
+	path := filepath.Join(t.TempDir(), "ids.txt")
+	list := "# tonight\nt100000000000000000000\n\nspotify:track:t200000000000000000000\nhttps://open.spotify.com/track/t300000000000000000000?si=abc\nnot an id\nt300000000\nt900000000000000000000\n"
+	if err := ioutil.WriteFile(path, []byte(list), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := execute(t, srv, "aid", "--from-file", path, "--p", "Bulk")
+	if err == nil || err.Error() != "3 tracks could not be added" {
+		t.Errorf("expected an error counting the failures, got %v", err)
+	}
+	if !strings.Contains(out, "Added 3 of 6 tracks") {
+		t.Errorf("expected a summary in output:\n%s", out)
+	}
+	if want := []spotify.ID{"t100000000000000000000", "t200000000000000000000", "t300000000000000000000"}; !reflect.DeepEqual(srv.PlaylistTracks(id), want) {
+		t.Errorf("got tracks %v, want %v", srv.PlaylistTracks(id), want)
+	}
+}
+
+func TestAddTrackIDsFromStdinInChunks(t *testing.T) {
+	srv := newTestServer(t)
+	id := srv.AddPlaylist(spotify.SimplePlaylist{Name: "Bulk"})
+
+	// more than fits in one request
+	var list strings.Builder
+	var want []spotify.ID
+	for i := 0; i < 250; i++ {
+		tid := spotify.ID(fmt.Sprintf("bulk%018d", i))
+		srv.AddTrack(testTrack(string(tid), "Song", "Artist", "Album", 200000, 10))
+		fmt.Fprintln(&list, tid)
+		want = append(want, tid)
+	}
+	stdin = strings.NewReader(list.String())
+	defer func() { stdin = origStdin }()
+
+	if _, err := execute(t, srv, "aid", "--from-file", "-", "--p", "Bulk"); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.PlaylistTracks(id); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %d tracks, want %d in order", len(got), len(want))
+	}
+}
+
+func TestAddTrackIDsFromFileSkipsPresent(t *testing.T) {
+	srv := newTestServer(t)
+	id := srv.Playlists()[0].ID
+	stdin = strings.NewReader("t100000000000000000000\nt300000000000000000000\nt300000000000000000000\n")
+	defer func() { stdin = origStdin }()
+
+	// t1 is in Mix already, t3 is listed twice
+	out, err := execute(t, srv, "aid", "--from-file", "-", "--p", "Mix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Added 1 of 3 tracks") {
+		t.Errorf("expected a summary in output:\n%s", out)
+	}
+	if want := []spotify.ID{"t100000000000000000000", "t200000000000000000000", "t300000000000000000000"}; !reflect.DeepEqual(srv.PlaylistTracks(id), want) {
+		t.Errorf("got tracks %v, want %v", srv.PlaylistTracks(id), want)
+	}
+}
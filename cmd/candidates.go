@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify"
+)
+
+// albumBatchSize is the most albums the Web API returns details of at once.
+const albumBatchSize = 20
+
+// trackCandidate is a search result that may be the track the user meant.
+type trackCandidate struct {
+	track spotify.FullTrack
+	// year is the release year of the album, when known.
+	year string
+}
+
+// newTrackCandidates returns candidates for tracks, in order. With years set,
+// it looks up the release year of their albums.
+func newTrackCandidates(tracks []spotify.FullTrack, years bool) ([]trackCandidate, error) {
+	candidates := make([]trackCandidate, len(tracks))
+	for i, t := range tracks {
+		candidates[i] = trackCandidate{track: t}
+	}
+	if !years {
+		return candidates, nil
+	}
+
+	released, err := albumReleaseYears(tracks)
+	if err != nil {
+		return nil, err
+	}
+	for i := range candidates {
+		candidates[i].year = released[candidates[i].track.Album.ID]
+	}
+	return candidates, nil
+}
+
+// albumReleaseYears returns the release years of the albums of tracks by
+// album ID. The search results don't carry them.
+func albumReleaseYears(tracks []spotify.FullTrack) (map[spotify.ID]string, error) {
+	years, err := albumReleaseDates(tracks)
+	if err != nil {
+		return nil, err
+	}
+	for id, date := range years {
+		// release dates are YYYY, YYYY-MM or YYYY-MM-DD
+		if len(date) >= 4 {
+			years[id] = date[:4]
+		}
+	}
+	return years, nil
+}
+
+// albumReleaseDates returns the release dates of the albums of tracks by
+// album ID, looking up albumBatchSize albums at a time.
+func albumReleaseDates(tracks []spotify.FullTrack) (map[spotify.ID]string, error) {
+	var ids []spotify.ID
+	dates := make(map[spotify.ID]string)
+	for _, t := range tracks {
+		if _, ok := dates[t.Album.ID]; !ok && t.Album.ID != "" {
+			dates[t.Album.ID] = ""
+			ids = append(ids, t.Album.ID)
+		}
+	}
+
+	for start := 0; start < len(ids); start += albumBatchSize {
+		end := start + albumBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		albums, err := client.GetAlbums(ids[start:end]...)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range albums {
+			if a != nil {
+				dates[a.ID] = a.ReleaseDate
+			}
+		}
+	}
+	return dates, nil
+}
+
+// filterTrackCandidates keeps the candidates with an artist and album
+// containing artist and album, ignoring case, released in year. Empty
+// filters keep everything.
+func filterTrackCandidates(candidates []trackCandidate, artist, album, year string) []trackCandidate {
+	var kept []trackCandidate
+	for _, c := range candidates {
+		if artist != "" && !anyContainsFold(artistNames(c.track.Artists), artist) {
+			continue
+		}
+		if album != "" && !containsFold(c.track.Album.Name, album) {
+			continue
+		}
+		if year != "" && c.year != year {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// describeTrackCandidates lists candidates numbered from 1, with their
+// artist, album, duration and year.
+func describeTrackCandidates(candidates []trackCandidate) string {
+	var b strings.Builder
+	for i, c := range candidates {
+		duration := (time.Duration(c.track.Duration) * time.Millisecond).Truncate(time.Second)
+		fmt.Fprintf(&b, "  %d) %s  %s  %s  %s  %s\n", i+1, c.track.Name, firstOf(artistNames(c.track.Artists)), c.track.Album.Name, duration, c.year)
+	}
+	return b.String()
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func anyContainsFold(list []string, substr string) bool {
+	for _, s := range list {
+		if containsFold(s, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/zmb3/spotify"
+)
+
+// spotifyClient is the subset of the Spotify Web API the commands use.
+// *webAPIClient implements it. The playlist methods take the user ID the
+// library's did, but reach playlists by ID alone, so those of other users
+// work too.
+type spotifyClient interface {
+	CurrentUser() (*spotify.PrivateUser, error)
+	Search(query string, t spotify.SearchType) (*spotify.SearchResult, error)
+	SearchOpt(query string, t spotify.SearchType, opt *spotify.Options) (*spotify.SearchResult, error)
+	GetAlbums(ids ...spotify.ID) ([]*spotify.FullAlbum, error)
+	GetTrack(id spotify.ID) (*spotify.FullTrack, error)
+	GetTracks(ids ...spotify.ID) ([]*spotify.FullTrack, error)
+	GetAudioFeatures(ids ...spotify.ID) ([]*spotify.AudioFeatures, error)
+	PlayerCurrentlyPlaying() (*spotify.CurrentlyPlaying, error)
+	CurrentUsersPlaylistsOpt(opt *spotify.Options) (*spotify.SimplePlaylistPage, error)
+	GetPlaylistOpt(userID string, playlistID spotify.ID, fields string) (*spotify.FullPlaylist, error)
+	GetPlaylistTracksOpt(userID string, playlistID spotify.ID, opt *spotify.Options, fields string) (*spotify.PlaylistTrackPage, error)
+	CreatePlaylistForUser(userID, playlistName string, public bool) (*spotify.FullPlaylist, error)
+	UnfollowPlaylist(owner, playlist spotify.ID) error
+	AddTracksToPlaylist(userID string, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error)
+	ReorderPlaylistTracks(userID string, playlistID spotify.ID, opt spotify.PlaylistReorderOptions) (string, error)
+	ReplacePlaylistTracks(userID string, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error)
+	RemoveTracksFromPlaylistOpt(userID string, playlistID spotify.ID, tracks []spotify.TrackToRemove, snapshotID string) (string, error)
+}
+
+// webAPIClient sends Web API requests through an http.Client, which
+// authenticates them. The library's spotify.Client only comes out of an
+// Authenticator, which hides its transport and token source, so the requests
+// are made here with the library's types.
+type webAPIClient struct {
+	http    *http.Client
+	baseURL string
+}
+
+// newWebAPIClient returns a client of the Web API sending its requests
+// through hc.
+func newWebAPIClient(hc *http.Client) *webAPIClient {
+	return &webAPIClient{http: hc, baseURL: apiURL}
+}
+
+func (c *webAPIClient) CurrentUser() (*spotify.PrivateUser, error) {
+	var user spotify.PrivateUser
+	return &user, c.get("me", nil, &user)
+}
+
+func (c *webAPIClient) Search(query string, t spotify.SearchType) (*spotify.SearchResult, error) {
+	return c.SearchOpt(query, t, nil)
+}
+
+func (c *webAPIClient) SearchOpt(query string, t spotify.SearchType, opt *spotify.Options) (*spotify.SearchResult, error) {
+	v := optionValues(opt)
+	v.Set("q", query)
+	v.Set("type", searchTypes(t))
+	var result spotify.SearchResult
+	return &result, c.get("search", v, &result)
+}
+
+func (c *webAPIClient) GetAlbums(ids ...spotify.ID) ([]*spotify.FullAlbum, error) {
+	var result struct {
+		Albums []*spotify.FullAlbum `json:"albums"`
+	}
+	err := c.get("albums", url.Values{"ids": {joinIDs(ids)}}, &result)
+	return result.Albums, err
+}
+
+func (c *webAPIClient) GetTrack(id spotify.ID) (*spotify.FullTrack, error) {
+	var track spotify.FullTrack
+	return &track, c.get("tracks/"+string(id), nil, &track)
+}
+
+func (c *webAPIClient) GetTracks(ids ...spotify.ID) ([]*spotify.FullTrack, error) {
+	var result struct {
+		Tracks []*spotify.FullTrack `json:"tracks"`
+	}
+	err := c.get("tracks", url.Values{"ids": {joinIDs(ids)}}, &result)
+	return result.Tracks, err
+}
+
+func (c *webAPIClient) GetAudioFeatures(ids ...spotify.ID) ([]*spotify.AudioFeatures, error) {
+	var result struct {
+		Features []*spotify.AudioFeatures `json:"audio_features"`
+	}
+	err := c.get("audio-features", url.Values{"ids": {joinIDs(ids)}}, &result)
+	return result.Features, err
+}
+
+// PlayerCurrentlyPlaying returns no item when nothing is playing.
+func (c *webAPIClient) PlayerCurrentlyPlaying() (*spotify.CurrentlyPlaying, error) {
+	var playing spotify.CurrentlyPlaying
+	return &playing, c.get("me/player/currently-playing", nil, &playing)
+}
+
+func (c *webAPIClient) CurrentUsersPlaylistsOpt(opt *spotify.Options) (*spotify.SimplePlaylistPage, error) {
+	var page spotify.SimplePlaylistPage
+	return &page, c.get("me/playlists", optionValues(opt), &page)
+}
+
+func (c *webAPIClient) GetPlaylistOpt(userID string, playlistID spotify.ID, fields string) (*spotify.FullPlaylist, error) {
+	v := url.Values{}
+	if fields != "" {
+		v.Set("fields", fields)
+	}
+	var playlist spotify.FullPlaylist
+	return &playlist, c.get(playlistPath(playlistID), v, &playlist)
+}
+
+func (c *webAPIClient) GetPlaylistTracksOpt(userID string, playlistID spotify.ID, opt *spotify.Options, fields string) (*spotify.PlaylistTrackPage, error) {
+	v := optionValues(opt)
+	if fields != "" {
+		v.Set("fields", fields)
+	}
+	var page spotify.PlaylistTrackPage
+	return &page, c.get(playlistPath(playlistID)+"/tracks", v, &page)
+}
+
+func (c *webAPIClient) CreatePlaylistForUser(userID, playlistName string, public bool) (*spotify.FullPlaylist, error) {
+	body := struct {
+		Name   string `json:"name"`
+		Public bool   `json:"public"`
+	}{playlistName, public}
+	var playlist spotify.FullPlaylist
+	return &playlist, c.send("POST", "users/"+userID+"/playlists", nil, body, &playlist)
+}
+
+func (c *webAPIClient) UnfollowPlaylist(owner, playlist spotify.ID) error {
+	return c.send("DELETE", playlistPath(playlist)+"/followers", nil, nil, nil)
+}
+
+func (c *webAPIClient) AddTracksToPlaylist(userID string, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error) {
+	body := map[string][]string{"uris": trackURIs(trackIDs)}
+	var result snapshotResult
+	err := c.send("POST", playlistPath(playlistID)+"/tracks", nil, body, &result)
+	return result.SnapshotID, err
+}
+
+func (c *webAPIClient) ReorderPlaylistTracks(userID string, playlistID spotify.ID, opt spotify.PlaylistReorderOptions) (string, error) {
+	var result snapshotResult
+	err := c.send("PUT", playlistPath(playlistID)+"/tracks", nil, opt, &result)
+	return result.SnapshotID, err
+}
+
+func (c *webAPIClient) ReplacePlaylistTracks(userID string, playlistID spotify.ID, trackIDs ...spotify.ID) (string, error) {
+	v := url.Values{"uris": {strings.Join(trackURIs(trackIDs), ",")}}
+	var result snapshotResult
+	err := c.send("PUT", playlistPath(playlistID)+"/tracks", v, nil, &result)
+	return result.SnapshotID, err
+}
+
+func (c *webAPIClient) RemoveTracksFromPlaylistOpt(userID string, playlistID spotify.ID, tracks []spotify.TrackToRemove, snapshotID string) (string, error) {
+	body := struct {
+		Tracks     []spotify.TrackToRemove `json:"tracks"`
+		SnapshotID string                  `json:"snapshot_id,omitempty"`
+	}{tracks, snapshotID}
+	var result snapshotResult
+	err := c.send("DELETE", playlistPath(playlistID)+"/tracks", nil, body, &result)
+	return result.SnapshotID, err
+}
+
+// snapshotResult is the answer to playlist changes.
+type snapshotResult struct {
+	SnapshotID string `json:"snapshot_id"`
+}
+
+func (c *webAPIClient) get(path string, v url.Values, result interface{}) error {
+	return c.send("GET", path, v, nil, result)
+}
+
+// send makes a request to path with the query v and body encoded as JSON,
+// if any, then decodes the response into result.
+func (c *webAPIClient) send(method, path string, v url.Values, body, result interface{}) error {
+	u := c.baseURL + path
+	if len(v) > 0 {
+		u += "?" + v.Encode()
+	}
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeError(resp)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	// some answers have no body, such as 204 No Content
+	if result == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
+
+// decodeError returns the error the Web API answered with.
+func decodeError(resp *http.Response) error {
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var e struct {
+		E spotify.Error `json:"error"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil || e.E.Message == "" {
+		return spotify.Error{Status: resp.StatusCode, Message: fmt.Sprintf("spotify: HTTP %d: %s", resp.StatusCode, http.StatusText(resp.StatusCode))}
+	}
+	return e.E
+}
+
+// optionValues returns the query parameters for opt.
+func optionValues(opt *spotify.Options) url.Values {
+	v := url.Values{}
+	if opt == nil {
+		return v
+	}
+	if opt.Country != nil {
+		v.Set("market", *opt.Country)
+	}
+	if opt.Limit != nil {
+		v.Set("limit", strconv.Itoa(*opt.Limit))
+	}
+	if opt.Offset != nil {
+		v.Set("offset", strconv.Itoa(*opt.Offset))
+	}
+	return v
+}
+
+// searchTypes returns the type parameter of a search for t.
+func searchTypes(t spotify.SearchType) string {
+	var types []string
+	for _, st := range []struct {
+		t    spotify.SearchType
+		name string
+	}{
+		{spotify.SearchTypeAlbum, "album"},
+		{spotify.SearchTypeArtist, "artist"},
+		{spotify.SearchTypePlaylist, "playlist"},
+		{spotify.SearchTypeTrack, "track"},
+	} {
+		if t&st.t != 0 {
+			types = append(types, st.name)
+		}
+	}
+	return strings.Join(types, ",")
+}
+
+func playlistPath(playlistID spotify.ID) string {
+	return "playlists/" + string(playlistID)
+}
+
+func joinIDs(ids []spotify.ID) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = string(id)
+	}
+	return strings.Join(s, ",")
+}
+
+func trackURIs(ids []spotify.ID) []string {
+	uris := make([]string, len(ids))
+	for i, id := range ids {
+		uris[i] = "spotify:track:" + string(id)
+	}
+	return uris
+}
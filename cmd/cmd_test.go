@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/masroorhasan/spotifycli/internal/spotifytest"
+	"github.com/spf13/cobra"
+	"github.com/zmb3/spotify"
+)
+
+// newTestServer starts a fake Web API seeded with the current user, a small
+// catalog and two playlists: "Mix" holding t1 and t2, and an empty "Empty".
+func newTestServer(t *testing.T) *spotifytest.Server {
+	srv := spotifytest.NewServer()
+	t.Cleanup(srv.Close)
+
+	srv.AddUser(spotify.PrivateUser{User: spotify.User{ID: "alice", DisplayName: "Alice"}})
+	srv.AddTrack(testTrack("t100000000000000000000", "Numb", "Linkin Park", "Meteora", 185000, 80))
+	srv.AddTrack(testTrack("t200000000000000000000", "Faint", "Linkin Park", "Meteora", 162000, 70))
+	srv.AddTrack(testTrack("t300000000000000000000", "Numb - Live", "Linkin Park", "Road to Revolution", 190000, 40))
+	srv.AddAlbum(testAlbum("Meteora", "2003-03-25"))
+	srv.AddAlbum(testAlbum("Road to Revolution", "2008-11-24"))
+	srv.AddArtist(spotify.FullArtist{
+		SimpleArtist: spotify.SimpleArtist{ID: "a1", Name: "Linkin Park"},
+		Genres:       []string{"alternative metal", "nu metal"},
+		Followers:    spotify.Followers{Count: 1000},
+	})
+	srv.AddPlaylist(spotify.SimplePlaylist{Name: "Mix"}, "t100000000000000000000", "t200000000000000000000")
+	srv.AddPlaylist(spotify.SimplePlaylist{Name: "Empty"})
+	return srv
+}
+
+// albumID derives an album ID from its name, without spaces to be usable in
+// URLs.
+func albumID(name string) spotify.ID {
+	return spotify.ID("al-" + strings.Replace(name, " ", "", -1))
+}
+
+func testAlbum(name, released string) spotify.FullAlbum {
+	return spotify.FullAlbum{
+		SimpleAlbum:          spotify.SimpleAlbum{ID: albumID(name), Name: name, AlbumType: "album"},
+		ReleaseDate:          released,
+		ReleaseDatePrecision: "day",
+	}
+}
+
+func testTrack(id, name, artist, album string, duration, popularity int) spotify.FullTrack {
+	return spotify.FullTrack{
+		SimpleTrack: spotify.SimpleTrack{
+			ID:       spotify.ID(id),
+			Name:     name,
+			Artists:  []spotify.SimpleArtist{{ID: spotify.ID("ar-" + artist), Name: artist}},
+			Duration: duration,
+		},
+		Album:      spotify.SimpleAlbum{ID: albumID(album), Name: album, AlbumType: "album"},
+		Popularity: popularity,
+	}
+}
+
+// execute runs the root command with args against srv, skipping
+// authentication, and returns what was written to stdout.
+func execute(t *testing.T, srv *spotifytest.Server, args ...string) (string, error) {
+	target, err := url.Parse(srv.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hc := &http.Client{Transport: &rewriteTransport{target: target, base: srv.Client().Transport}}
+	return executeWith(t, hc, args...)
+}
+
+// executeWith is like execute but sends requests for the Web API through hc.
+func executeWith(t *testing.T, hc *http.Client, args ...string) (string, error) {
+	var out, errOut bytes.Buffer
+	stdout, stderr = &out, &errOut
+	defer func() {
+		stdout, stderr = origStdout, origStderr
+	}()
+
+	root := NewRootCmd()
+	root.PersistentPreRun = nil
+	root.PersistentPostRun = nil
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := validateOutput(); err != nil {
+			return err
+		}
+		client = newWebAPIClient(hc)
+		return nil
+	}
+	root.SilenceUsage, root.SilenceErrors = true, true
+	root.SetOutput(&errOut)
+	root.SetArgs(args)
+	err := root.Execute()
+	if testing.Verbose() && errOut.Len() > 0 {
+		fmt.Print(errOut.String())
+	}
+	return out.String(), err
+}
+
+var (
+	origStdout        = stdout
+	origStderr        = stderr
+	origStdin         = stdin
+	origIsInteractive = isInteractive
+)
+
+// rewriteTransport sends requests for the Web API to target instead, keeping
+// the original host so recorded URLs and links look like Spotify's.
+type rewriteTransport struct {
+	target *url.URL
+	base   http.RoundTripper
+}
+
+func (rt *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.Host = req.URL.Host
+	r.URL.Scheme, r.URL.Host = rt.target.Scheme, rt.target.Host
+	resp, err := rt.base.RoundTrip(r)
+	if resp != nil {
+		resp.Request = req
+	}
+	return resp, err
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+const (
+	appName    = "spotifycli"
+	configFile = "config.json"
+
+	// defaultProfile names the profile made of the top level settings of the
+	// config file.
+	defaultProfile = "default"
+	// profileEnv selects the profile when --profile is not given.
+	profileEnv = "SPOTIFYCLI_PROFILE"
+)
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// checkProfileName returns an error unless name is fit to name a profile,
+// whose token is kept in a directory of that name.
+func checkProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q, use letters, digits, - and _", name)
+	}
+	return nil
+}
+
+// config holds the settings read from the config file. Environment
+// variables and flags take precedence over it.
+type config struct {
+	profile
+	// Profile is the profile selected by "profile use".
+	Profile string `json:"profile,omitempty"`
+	// Profiles holds the named profiles besides the default one.
+	Profiles map[string]profile `json:"profiles,omitempty"`
+	// RedirectURI replaces the default redirect URI registered for the
+	// application. The login callback server listens on its host and port.
+	RedirectURI string `json:"redirect_uri,omitempty"`
+	// Output is the default output format.
+	Output string `json:"output,omitempty"`
+}
+
+// profile holds the settings of one Spotify account.
+type profile struct {
+	// ClientID and ClientSecret identify the Spotify application, like the
+	// SPOTIFY_ID and SPOTIFY_SECRET environment variables.
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	// Market is the country code searches are made for.
+	Market string `json:"market,omitempty"`
+}
+
+// selectedProfile returns the name of the profile to use: the --profile
+// flag, profileEnv or the one selected by "profile use", in that order.
+func (c *config) selectedProfile() string {
+	if profileName != "" {
+		return profileName
+	}
+	if name := os.Getenv(profileEnv); name != "" {
+		return name
+	}
+	if c.Profile != "" {
+		return c.Profile
+	}
+	return defaultProfile
+}
+
+// lookupProfile returns the profile called name. The environment variables
+// override the credentials of the default profile, and fill in the ones
+// missing from a named profile.
+func (c *config) lookupProfile(name string) (profile, error) {
+	if name == defaultProfile {
+		p := c.profile
+		p.ClientID = getenvOr("SPOTIFY_ID", p.ClientID)
+		p.ClientSecret = getenvOr("SPOTIFY_SECRET", p.ClientSecret)
+		return p, nil
+	}
+	if err := checkProfileName(name); err != nil {
+		return profile{}, err
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return profile{}, fmt.Errorf("profile not found: %s", name)
+	}
+	if p.ClientID == "" {
+		p.ClientID = os.Getenv("SPOTIFY_ID")
+	}
+	if p.ClientSecret == "" {
+		p.ClientSecret = os.Getenv("SPOTIFY_SECRET")
+	}
+	return p, nil
+}
+
+// profileNames returns the names of all profiles, the default one first.
+func (c *config) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{defaultProfile}, names...)
+}
+
+// configDir returns the directory holding the config file and token:
+// $XDG_CONFIG_HOME/spotifycli, or ~/.config/spotifycli when unset.
+func configDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, appName), nil
+	}
+	home, err := homeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", appName), nil
+}
+
+func homeDir() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return u.HomeDir, nil
+}
+
+// configPath returns the path of the config file.
+func configPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configFile), nil
+}
+
+// loadConfig reads the config file. A missing file yields an empty config.
+func loadConfig() (*config, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg config
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	warnIfTooOpen(path)
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &cfg, nil
+}
+
+// saveConfig writes cfg to the config file, only readable by the user.
+func saveConfig(cfg *config) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(b, '\n'))
+}
+
+// warnIfTooOpen warns when a file holding credentials can be read or written
+// by users other than its owner.
+func warnIfTooOpen(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		fmt.Fprintf(stderr, "Warning: %s is accessible by other users (%#o), run: chmod 600 %s\n", path, perm, path)
+	}
+}
+
+func getenvOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
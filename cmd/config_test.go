@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setConfigHome points XDG_CONFIG_HOME at a temporary directory for the test
+// and returns it.
+func setConfigHome(t *testing.T) string {
+	dir := t.TempDir()
+	orig, ok := os.LookupEnv("XDG_CONFIG_HOME")
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv("XDG_CONFIG_HOME", orig)
+		} else {
+			os.Unsetenv("XDG_CONFIG_HOME")
+		}
+	})
+	return dir
+}
+
+func TestLoadConfig(t *testing.T) {
+	home := setConfigHome(t)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*cfg, config{}) {
+		t.Errorf("expected empty config without a file, got %+v", cfg)
+	}
+
+	dir := filepath.Join(home, appName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	data := `{"client_id": "id", "client_secret": "secret", "output": "json"}`
+	if err := ioutil.WriteFile(filepath.Join(dir, configFile), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var errOut bytes.Buffer
+	stderr = &errOut
+	defer func() { stderr = origStderr }()
+
+	cfg, err = loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := config{profile: profile{ClientID: "id", ClientSecret: "secret"}, Output: "json"}
+	if !reflect.DeepEqual(*cfg, want) {
+		t.Errorf("got %+v, want %+v", *cfg, want)
+	}
+	if !strings.Contains(errOut.String(), "accessible by other users") {
+		t.Errorf("expected a warning about permissions, got %q", errOut.String())
+	}
+}
+
+func TestTokenStoreMigratesLegacyToken(t *testing.T) {
+	home := setConfigHome(t)
+	legacy := filepath.Join(t.TempDir(), legacyTokenFile)
+	if err := ioutil.WriteFile(legacy, []byte(`{"access_token":"old"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var errOut bytes.Buffer
+	stderr = &errOut
+	defer func() { stderr = origStderr }()
+
+	store := &tokenStore{path: filepath.Join(home, appName, tokenFile)}
+	if err := store.migrate(legacy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("expected legacy token to be removed, got %v", err)
+	}
+
+	info, err := os.Stat(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("got token permissions %#o, want 0600", perm)
+	}
+	token, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "old" {
+		t.Errorf("got access token %q, want the migrated one", token.AccessToken)
+	}
+	if strings.Contains(errOut.String(), "Warning") {
+		t.Errorf("unexpected warning: %q", errOut.String())
+	}
+
+	// an existing token is not replaced
+	if err := ioutil.WriteFile(legacy, []byte(`{"access_token":"older"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.migrate(legacy); err != nil {
+		t.Fatal(err)
+	}
+	if token, _ := store.Load(); token.AccessToken != "old" {
+		t.Errorf("existing token was replaced by %q", token.AccessToken)
+	}
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zmb3/spotify"
+)
+
+var (
+	dedupePlaylistName string
+	dedupeFuzzy        bool
+	dedupeTolerance    time.Duration
+	dedupeDryRun       bool
+)
+
+func newDedupePlaylistCmd() *cobra.Command {
+	dedupeCmd := &cobra.Command{
+		Use:         "dedupe --p [PLAYLIST_NAME]",
+		Short:       "Remove duplicate tracks from playlist",
+		Annotations: needsScopes(playlistModifyScopes...),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return dedupePlaylist(cmd, args)
+		},
+	}
+	dedupeCmd.Flags().StringVar(&dedupePlaylistName, "p", "", "Name, ID, URI or URL of playlist to remove duplicates from.")
+	dedupeCmd.Flags().BoolVar(&dedupeFuzzy, "fuzzy", false, "Also find the same song under different IDs, by ISRC or by title, artist and duration.")
+	dedupeCmd.Flags().DurationVar(&dedupeTolerance, "tolerance", 3*time.Second, "Largest difference in duration between fuzzy duplicates.")
+	dedupeCmd.Flags().BoolVar(&dedupeDryRun, "dry-run", false, "List the duplicates without removing them.")
+	addPlaylistSelectorFlags(dedupeCmd.Flags())
+	return dedupeCmd
+}
+
+func dedupePlaylist(cmd *cobra.Command, args []string) error {
+	if dedupeTolerance < 0 {
+		return errors.New("--tolerance must not be negative")
+	}
+
+	// current user
+	user, err := client.CurrentUser()
+	if err != nil {
+		return err
+	}
+	printInfo("User: ", user.DisplayName)
+
+	// get the playlist
+	pl, err := resolvePlaylist(dedupePlaylistName)
+	if err != nil {
+		return err
+	}
+	printInfo("Playlist: ", pl.Name)
+
+	// find the duplicates of earlier occurrences
+	entries, err := readPlaylistEntries(user.ID, pl.ID)
+	if err != nil {
+		return err
+	}
+	duplicates := findDuplicates(entries, dedupeFuzzy, dedupeTolerance)
+	if len(duplicates) == 0 {
+		printInfof("No duplicates in playlist \"%s\".\n", pl.Name)
+		return nil
+	}
+	for _, d := range duplicates {
+		printInfof("Duplicate: %d) %s - %s, %s of %d) %s - %s\n",
+			d.position+1, firstOf(artistNames(d.track.Artists)), d.track.Name, d.reason,
+			d.of.position+1, firstOf(artistNames(d.of.track.Artists)), d.of.track.Name)
+	}
+	if dedupeDryRun {
+		printInfof("Would remove %d duplicates from playlist \"%s\".\n", len(duplicates), pl.Name)
+		return nil
+	}
+
+	// remove them, keeping the first occurrences
+	removed := make([]playlistEntry, 0, len(duplicates))
+	for _, d := range duplicates {
+		removed = append(removed, d.playlistEntry)
+	}
+	if _, err := removePlaylistEntries(user.ID, pl, removed); err != nil {
+		return err
+	}
+	printInfof("Removed %d duplicates from playlist \"%s\".\n", len(duplicates), pl.Name)
+	return nil
+}
+
+// duplicate is an entry found again later in a playlist.
+type duplicate struct {
+	playlistEntry
+	of     playlistEntry
+	reason string
+}
+
+// findDuplicates returns the entries repeating an earlier one: the same
+// track or, when fuzzy, the same ISRC or the same normalized title and
+// artist with durations within tolerance. Local files, without an ID, are
+// left alone.
+func findDuplicates(entries []playlistEntry, fuzzy bool, tolerance time.Duration) []duplicate {
+	byID := make(map[spotify.ID]playlistEntry)
+	byISRC := make(map[string]playlistEntry)
+	byTitle := make(map[string][]playlistEntry)
+
+	var duplicates []duplicate
+	for _, e := range entries {
+		if e.track.ID == "" {
+			continue
+		}
+		if first, ok := byID[e.track.ID]; ok {
+			duplicates = append(duplicates, duplicate{e, first, "same track"})
+			continue
+		}
+		byID[e.track.ID] = e
+		if !fuzzy {
+			continue
+		}
+
+		isrc := e.track.ExternalIDs["isrc"]
+		if first, ok := byISRC[isrc]; ok && isrc != "" {
+			duplicates = append(duplicates, duplicate{e, first, "same ISRC"})
+			continue
+		}
+		key := normalizeTitle(e.track.Name) + "\x00" + normalizeTitle(firstOf(artistNames(e.track.Artists)))
+		if first, ok := sameDuration(byTitle[key], e, tolerance); ok {
+			duplicates = append(duplicates, duplicate{e, first, "same title, artist and duration"})
+			continue
+		}
+		if isrc != "" {
+			byISRC[isrc] = e
+		}
+		byTitle[key] = append(byTitle[key], e)
+	}
+	return duplicates
+}
+
+// sameDuration returns the first of candidates lasting as long as e, within
+// tolerance.
+func sameDuration(candidates []playlistEntry, e playlistEntry, tolerance time.Duration) (playlistEntry, bool) {
+	for _, c := range candidates {
+		diff := time.Duration(c.track.Duration-e.track.Duration) * time.Millisecond
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff <= tolerance {
+			return c, true
+		}
+	}
+	return playlistEntry{}, false
+}
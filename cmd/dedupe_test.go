@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zmb3/spotify"
+)
+
+func TestDedupePlaylist(t *testing.T) {
+	srv := newTestServer(t)
+	// the same recording of Faint under two IDs
+	for _, id := range []string{"t200000000000000000000", "t500000000000000000000"} {
+		faint := testTrack(id, "Faint", "Linkin Park", "Meteora", 162000, 70)
+		faint.ExternalIDs = map[string]string{"isrc": "USWB10300001"}
+		srv.AddTrack(faint)
+	}
+	srv.AddTrack(testTrack("t600000000000000000000", "Numb (Remastered)", "LINKIN PARK", "Meteora 20th Anniversary", 187000, 60))
+	srv.AddTrack(testTrack("t700000000000000000000", "Numb", "Linkin Park", "Numb/Encore", 205000, 30))
+
+	tests := []struct {
+		name string
+		args []string
+		want []spotify.ID
+	}{
+		{"exact", nil, []spotify.ID{"t100000000000000000000", "t200000000000000000000", "t500000000000000000000", "t600000000000000000000", "t700000000000000000000"}},
+		{"fuzzy", []string{"--fuzzy"}, []spotify.ID{"t100000000000000000000", "t200000000000000000000", "t700000000000000000000"}},
+		{"dry run", []string{"--fuzzy", "--dry-run"}, []spotify.ID{"t100000000000000000000", "t200000000000000000000", "t100000000000000000000", "t500000000000000000000", "t600000000000000000000", "t700000000000000000000", "t200000000000000000000"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id := srv.AddPlaylist(spotify.SimplePlaylist{Name: test.name}, "t100000000000000000000", "t200000000000000000000", "t100000000000000000000", "t500000000000000000000", "t600000000000000000000", "t700000000000000000000", "t200000000000000000000")
+			out, err := execute(t, srv, append([]string{"dedupe", "--p", test.name}, test.args...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := srv.PlaylistTracks(id); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got tracks %v, want %v", got, test.want)
+			}
+			if !strings.Contains(out, "Duplicate: 3) Linkin Park - Numb, same track of 1) Linkin Park - Numb") {
+				t.Errorf("expected the duplicates in output:\n%s", out)
+			}
+		})
+	}
+}
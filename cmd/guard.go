@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+	"github.com/zmb3/spotify"
+)
+
+// allowDuplicates is the --allow-duplicates flag of the add commands.
+var allowDuplicates bool
+
+func addAllowDuplicatesFlag(flags *pflag.FlagSet) {
+	flags.BoolVar(&allowDuplicates, "allow-duplicates", false, "Add tracks even when already in the playlist.")
+}
+
+// playlistTrackIDs is the set of tracks in a playlist, read on first use so
+// that adding many tracks pages through the playlist only once.
+type playlistTrackIDs struct {
+	userID     string
+	playlistID spotify.ID
+	ids        map[spotify.ID]bool
+}
+
+func newPlaylistTrackIDs(userID string, playlistID spotify.ID) *playlistTrackIDs {
+	return &playlistTrackIDs{userID: userID, playlistID: playlistID}
+}
+
+// contains reports whether the playlist has track id.
+func (s *playlistTrackIDs) contains(id spotify.ID) (bool, error) {
+	if s.ids == nil {
+		if err := s.load(); err != nil {
+			return false, err
+		}
+	}
+	return s.ids[id], nil
+}
+
+// add records that track id is now in the playlist.
+func (s *playlistTrackIDs) add(id spotify.ID) {
+	if s.ids == nil {
+		s.ids = make(map[spotify.ID]bool)
+	}
+	s.ids[id] = true
+}
+
+// load reads the IDs of the playlist tracks, one page at a time, asking
+// only for the IDs.
+func (s *playlistTrackIDs) load() error {
+	ids := make(map[spotify.ID]bool)
+	limit, offset := playlistTrackPageLimit, 0
+	for {
+		page, err := client.GetPlaylistTracksOpt(s.userID, s.playlistID,
+			&spotify.Options{Limit: &limit, Offset: &offset}, "items(track(id)),next")
+		if err != nil {
+			return err
+		}
+		for _, t := range page.Tracks {
+			ids[t.Track.ID] = true
+		}
+		offset += len(page.Tracks)
+		if page.Next == "" || len(page.Tracks) == 0 {
+			break
+		}
+	}
+	s.ids = ids
+	return nil
+}
+
+// skipPresent returns the tracks to add to the playlist pl: the ones not
+// in it yet, once each. It warns about the others. With --allow-duplicates
+// every track is added.
+func skipPresent(pl spotify.SimplePlaylist, present *playlistTrackIDs, tracks []*spotify.FullTrack) ([]*spotify.FullTrack, error) {
+	if allowDuplicates {
+		return tracks, nil
+	}
+	var missing []*spotify.FullTrack
+	for _, t := range tracks {
+		found, err := present.contains(t.ID)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			fmt.Fprintf(stderr, "Skipped track \"%s\", already in playlist \"%s\". Use --allow-duplicates to add it anyway.\n", t.Name, pl.Name)
+			continue
+		}
+		present.add(t.ID)
+		missing = append(missing, t)
+	}
+	return missing, nil
+}
+
+// addTracks adds tracks to the playlist pl in one request, skipping the ones
+// already there.
+func addTracks(userID string, pl spotify.SimplePlaylist, tracks []*spotify.FullTrack) error {
+	tracks, err := skipPresent(pl, newPlaylistTrackIDs(userID, pl.ID), tracks)
+	if err != nil || len(tracks) == 0 {
+		return err
+	}
+	ids := make([]spotify.ID, len(tracks))
+	for i, t := range tracks {
+		ids[i] = t.ID
+	}
+	if _, err := client.AddTracksToPlaylist(userID, pl.ID, ids...); err != nil {
+		return err
+	}
+	for _, t := range tracks {
+		printInfof("Added track \"%s\" to playlist \"%s\".\n", t.Name, pl.Name)
+	}
+	return nil
+}
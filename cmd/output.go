@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/bndr/gotabulate"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+	outputTSV   = "tsv"
+	outputYAML  = "yaml"
+)
+
+var (
+	outputFormat   string
+	outputTemplate string
+)
+
+// templateFuncs are the helpers available to --format templates.
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// view describes how records are laid out in tabular output (table, csv and
+// tsv). Each header is paired with the record field of the same index.
+type view struct {
+	headers []string
+	fields  []string
+}
+
+func validateOutput() error {
+	if outputTemplate != "" {
+		_, err := parseOutputTemplate()
+		return err
+	}
+
+	switch outputFormat {
+	case outputTable, outputJSON, outputCSV, outputTSV, outputYAML:
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format: %s (table, json, csv, tsv, yaml)", outputFormat)
+	}
+}
+
+// render writes records, a slice of record structs, to stdout in the
+// selected output format.
+func render(v view, records interface{}) error {
+	rv := reflect.ValueOf(records)
+	if rv.Kind() != reflect.Slice {
+		return fmt.Errorf("cannot render %T", records)
+	}
+
+	w, err := newRecordWriter(v)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := w.Write(rv.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// recordWriter writes records to stdout one at a time in the selected output
+// format, so long results needn't be held in memory. csv, tsv, json and
+// --format templates are written as the records come; a table and yaml need
+// every record first, so they are kept until Close.
+type recordWriter struct {
+	v       view
+	tmpl    *template.Template
+	csv     *csv.Writer
+	buf     *bufio.Writer
+	records reflect.Value
+	n       int
+}
+
+func newRecordWriter(v view) (*recordWriter, error) {
+	w := &recordWriter{v: v, buf: bufio.NewWriter(stdout)}
+	if outputTemplate != "" {
+		tmpl, err := parseOutputTemplate()
+		if err != nil {
+			return nil, err
+		}
+		w.tmpl = tmpl
+		return w, nil
+	}
+	switch outputFormat {
+	case outputCSV, outputTSV:
+		w.csv = csv.NewWriter(w.buf)
+		if outputFormat == outputTSV {
+			w.csv.Comma = '\t'
+		}
+	}
+	return w, nil
+}
+
+// Write writes record, a record struct.
+func (w *recordWriter) Write(record interface{}) error {
+	defer func() { w.n++ }()
+
+	switch {
+	case w.tmpl != nil:
+		// the template once per record, each on its own line
+		if err := w.tmpl.Execute(w.buf, record); err != nil {
+			return err
+		}
+		_, err := w.buf.WriteString("\n")
+		return err
+	case w.csv != nil:
+		if w.n == 0 {
+			if err := w.csv.Write(w.v.headers); err != nil {
+				return err
+			}
+		}
+		return w.csv.Write(cells(w.v, reflect.ValueOf(record)))
+	case outputFormat == outputJSON:
+		// the elements of an indented array
+		b, err := json.MarshalIndent(record, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		sep := ",\n  "
+		if w.n == 0 {
+			sep = "[\n  "
+		}
+		w.buf.WriteString(sep)
+		_, err = w.buf.Write(b)
+		return err
+	default:
+		rv := reflect.ValueOf(record)
+		if !w.records.IsValid() {
+			w.records = reflect.MakeSlice(reflect.SliceOf(rv.Type()), 0, 0)
+		}
+		w.records = reflect.Append(w.records, rv)
+		return nil
+	}
+}
+
+// Close ends the output, writing what was kept back.
+func (w *recordWriter) Close() error {
+	switch {
+	case w.tmpl != nil:
+	case w.csv != nil:
+		if w.n == 0 {
+			if err := w.csv.Write(w.v.headers); err != nil {
+				return err
+			}
+		}
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	case outputFormat == outputJSON:
+		// encode empty results as [] rather than null
+		if w.n == 0 {
+			w.buf.WriteString("[]\n")
+		} else {
+			w.buf.WriteString("\n]\n")
+		}
+	case outputFormat == outputYAML:
+		if w.n == 0 {
+			w.buf.WriteString("[]\n")
+			break
+		}
+		var b strings.Builder
+		writeYAML(&b, w.records, 0)
+		w.buf.WriteString(b.String())
+	default:
+		if err := w.buf.Flush(); err != nil {
+			return err
+		}
+		return renderTable(w.v, w.records)
+	}
+	return w.buf.Flush()
+}
+
+// printInfo prints informational lines such as the current user. They go to
+// stdout alongside tables, and to stderr when the output is meant for
+// machines so they don't corrupt it.
+func printInfo(a ...interface{}) {
+	fmt.Fprintln(infoWriter(), a...)
+}
+
+// printInfof is like printInfo but accepts a format specifier.
+func printInfof(format string, a ...interface{}) {
+	fmt.Fprintf(infoWriter(), format, a...)
+}
+
+func infoWriter() io.Writer {
+	if outputFormat == outputTable && outputTemplate == "" {
+		return stdout
+	}
+	return stderr
+}
+
+func renderTable(v view, rv reflect.Value) error {
+	// gotabulate can't render an empty table
+	if !rv.IsValid() || rv.Len() == 0 {
+		return nil
+	}
+
+	var data [][]interface{}
+	for i := 0; i < rv.Len(); i++ {
+		cells := cells(v, rv.Index(i))
+		row := make([]interface{}, len(cells))
+		for i, d := range cells {
+			row[i] = d
+		}
+		data = append(data, row)
+	}
+	printSimple(v.headers, data)
+	return nil
+}
+
+func parseOutputTemplate() (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(templateFuncs).Parse(outputTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// cells formats the fields of a record selected by v as strings.
+func cells(v view, record reflect.Value) []string {
+	cells := make([]string, len(v.fields))
+	for i, name := range v.fields {
+		cells[i] = formatCell(record.FieldByName(name))
+	}
+	return cells
+}
+
+func formatCell(f reflect.Value) string {
+	switch f.Kind() {
+	case reflect.Slice:
+		items := make([]string, f.Len())
+		for i := range items {
+			items[i] = formatCell(f.Index(i))
+		}
+		return strings.Join(items, ",")
+	case reflect.Invalid:
+		return ""
+	default:
+		return fmt.Sprint(f.Interface())
+	}
+}
+
+// writeYAML emits v as a block style YAML document. It only understands the
+// shapes records are made of: slices, structs with json tags and scalars.
+// Strings are always double quoted, so they never need escaping rules beyond
+// those of Go string literals.
+func writeYAML(b *strings.Builder, v reflect.Value, indent int) {
+	pad := strings.Repeat("  ", indent)
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Len() == 0 {
+			b.WriteString(pad + "[]\n")
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			var item strings.Builder
+			writeYAML(&item, v.Index(i), indent+1)
+			// replace the indentation of the first line with the item marker
+			b.WriteString(pad + "- " + strings.TrimPrefix(item.String(), pad+"  "))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			f := v.Field(i)
+			switch f.Kind() {
+			case reflect.Slice, reflect.Struct:
+				if f.Kind() == reflect.Slice && f.Len() == 0 {
+					b.WriteString(pad + name + ": []\n")
+					continue
+				}
+				b.WriteString(pad + name + ":\n")
+				writeYAML(b, f, indent+1)
+			default:
+				b.WriteString(pad + name + ": " + yamlScalar(f) + "\n")
+			}
+		}
+	default:
+		b.WriteString(pad + yamlScalar(v) + "\n")
+	}
+}
+
+func yamlScalar(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return strconv.Quote(v.String())
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func printSimple(headers []string, data [][]interface{}) {
+	tabulate := gotabulate.Create(data)
+	tabulate.SetHeaders(headers)
+	fmt.Fprintln(stdout, tabulate.Render("simple"))
+}
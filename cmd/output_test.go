@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestOutputFormats(t *testing.T) {
+	srv := newTestServer(t)
+
+	out, err := execute(t, srv, "search", "--t", "ar", "--q", "linkin", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var artists []artistRecord
+	if err := json.Unmarshal([]byte(out), &artists); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, out)
+	}
+	if len(artists) != 1 || len(artists[0].Genres) != 2 || artists[0].Followers != 1000 {
+		t.Errorf("unexpected artists: %+v", artists)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-o", "csv"}, "ID,Name,Album,Artist,Popularity\nt100000000000000000000,Numb,Meteora,Linkin Park,80\nt200000000000000000000,Faint,Meteora,Linkin Park,70\n"},
+		{[]string{"-o", "tsv"}, "ID\tName\tAlbum\tArtist\tPopularity\nt100000000000000000000\tNumb\tMeteora\tLinkin Park\t80\nt200000000000000000000\tFaint\tMeteora\tLinkin Park\t70\n"},
+		{[]string{"-o", "json"}, "[\n  {\n    \"id\": \"t100000000000000000000\",\n    \"name\": \"Numb\","},
+		{[]string{"-o", "yaml"}, "- id: \"t100000000000000000000\"\n  name: \"Numb\"\n  album: \"Meteora\"\n  artist: \"Linkin Park\"\n  artists:\n    - \"Linkin Park\"\n"},
+		{[]string{"--format", "{{.Name}} - {{.Artist}} ({{.DurationMs}})"}, "Numb - Linkin Park (185000)\nFaint - Linkin Park (162000)\n"},
+		{[]string{"--format", "{{join .Artists \"/\"}}"}, "Linkin Park\nLinkin Park\n"},
+	}
+	for _, test := range tests {
+		out, err := execute(t, srv, append([]string{"list", "--p", "Mix"}, test.args...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(out, test.want) {
+			t.Errorf("%v: got\n%s\nwant prefix\n%s", test.args, out, test.want)
+		}
+	}
+}
+
+func TestOutputEmptyResults(t *testing.T) {
+	srv := newTestServer(t)
+
+	for format, want := range map[string]string{"json": "[]\n", "yaml": "[]\n", "table": ""} {
+		out, err := execute(t, srv, "list", "--p", "Empty", "-o", format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the table output also carries the user line
+		if format == "table" {
+			out = strings.TrimPrefix(out, "User:  Alice\n")
+		}
+		if out != want {
+			t.Errorf("%s: got %q, want %q", format, out, want)
+		}
+	}
+}
+
+func TestOutputStreamsPages(t *testing.T) {
+	srv := newTestServer(t)
+	srv.PageSize = 1
+
+	// records written as each page comes still make up one document
+	out, err := execute(t, srv, "list", "--p", "Mix", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tracks []trackRecord
+	if err := json.Unmarshal([]byte(out), &tracks); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, out)
+	}
+	if len(tracks) != 2 || tracks[1].Name != "Faint" {
+		t.Errorf("unexpected tracks: %+v", tracks)
+	}
+
+	out, err = execute(t, srv, "list", "--p", "Empty", "-o", "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ID,Name,Album,Artist,Popularity\n"; out != want {
+		t.Errorf("got %q, want only the header %q", out, want)
+	}
+}
+
+func TestInvalidOutput(t *testing.T) {
+	srv := newTestServer(t)
+
+	if _, err := execute(t, srv, "playlists", "-o", "xml"); err == nil {
+		t.Error("expected error for unsupported output format")
+	}
+	if _, err := execute(t, srv, "playlists", "--format", "{{.Name"); err == nil {
+		t.Error("expected error for invalid template")
+	}
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"github.com/zmb3/spotify"
+)
+
+const (
+	// playlistPageLimit is the maximum page size of the playlists endpoint.
+	playlistPageLimit = 50
+	// playlistTrackPageLimit is the maximum page size of the playlist
+	// tracks endpoint.
+	playlistTrackPageLimit = 100
+)
+
+// playlistIterator walks the current user's playlists one page at a time,
+// following the Next link of each page until the library is exhausted.
+//
+// Usage:
+//
+//	it := newPlaylistIterator()
+//	for it.Next() {
+//	    p := it.Playlist()
+//	}
+//	if err := it.Err(); err != nil {
+//	    ...
+//	}
+type playlistIterator struct {
+	page   *spotify.SimplePlaylistPage
+	pos    int
+	offset int
+	err    error
+}
+
+func newPlaylistIterator() *playlistIterator {
+	return &playlistIterator{}
+}
+
+// Next advances to the next playlist, fetching the following page when the
+// current one has been consumed. It returns false when there are no more
+// playlists or an error occurred.
+func (it *playlistIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.page == nil || it.pos >= len(it.page.Playlists) {
+		// last page already consumed
+		if it.page != nil && it.page.Next == "" {
+			return false
+		}
+
+		limit, offset := playlistPageLimit, it.offset
+		page, err := client.CurrentUsersPlaylistsOpt(&spotify.Options{Limit: &limit, Offset: &offset})
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page, it.pos = page, 0
+		it.offset += len(page.Playlists)
+		if len(page.Playlists) == 0 {
+			return false
+		}
+	}
+	it.pos++
+	return true
+}
+
+// Playlist returns the playlist the iterator currently points at.
+func (it *playlistIterator) Playlist() spotify.SimplePlaylist {
+	return it.page.Playlists[it.pos-1]
+}
+
+// Total returns the number of playlists in the library as reported by the
+// last fetched page.
+func (it *playlistIterator) Total() int {
+	if it.page == nil {
+		return 0
+	}
+	return it.page.Total
+}
+
+// Err returns the error, if any, that stopped the iteration.
+func (it *playlistIterator) Err() error {
+	return it.err
+}
+
+// playlistTrackIterator walks the tracks of a playlist one page at a time.
+// Only the current page is held in memory, so arbitrarily large playlists
+// can be scanned. A non-zero limit stops the walk after that many tracks.
+type playlistTrackIterator struct {
+	userID     string
+	playlistID spotify.ID
+	limit      int
+
+	page   *spotify.PlaylistTrackPage
+	pos    int
+	offset int
+	seen   int
+	err    error
+}
+
+func newPlaylistTrackIterator(userID string, playlistID spotify.ID, offset, limit int) *playlistTrackIterator {
+	return &playlistTrackIterator{
+		userID:     userID,
+		playlistID: playlistID,
+		offset:     offset,
+		limit:      limit,
+	}
+}
+
+// Next advances to the next track, fetching the following page when the
+// current one has been consumed. It returns false when there are no more
+// tracks, the limit was reached or an error occurred.
+func (it *playlistTrackIterator) Next() bool {
+	if it.err != nil || (it.limit > 0 && it.seen >= it.limit) {
+		return false
+	}
+	if it.page == nil || it.pos >= len(it.page.Tracks) {
+		// last page already consumed
+		if it.page != nil && it.page.Next == "" {
+			return false
+		}
+
+		limit, offset := playlistTrackPageLimit, it.offset
+		if it.limit > 0 && it.limit-it.seen < limit {
+			limit = it.limit - it.seen
+		}
+		page, err := client.GetPlaylistTracksOpt(it.userID, it.playlistID,
+			&spotify.Options{Limit: &limit, Offset: &offset}, "")
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page, it.pos = page, 0
+		it.offset += len(page.Tracks)
+		if len(page.Tracks) == 0 {
+			return false
+		}
+	}
+	it.pos++
+	it.seen++
+	return true
+}
+
+// Track returns the playlist track the iterator currently points at.
+func (it *playlistTrackIterator) Track() spotify.PlaylistTrack {
+	return it.page.Tracks[it.pos-1]
+}
+
+// Position returns the 0-based position of the current track in the playlist.
+func (it *playlistTrackIterator) Position() int {
+	return it.offset - len(it.page.Tracks) + it.pos - 1
+}
+
+// Total returns the number of tracks in the playlist as reported by the
+// last fetched page.
+func (it *playlistTrackIterator) Total() int {
+	if it.page == nil {
+		return 0
+	}
+	return it.page.Total
+}
+
+// Err returns the error, if any, that stopped the iteration.
+func (it *playlistTrackIterator) Err() error {
+	return it.err
+}
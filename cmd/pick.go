@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// isInteractive reports whether the user can be asked to pick, that is when
+// stdin is a terminal. /dev/null is a character device too, as stdin of
+// cron jobs and tests, but nobody answers there.
+var isInteractive = func() bool {
+	f, ok := stdin.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
+
+// pickOne asks the user to pick one of the n numbered choices listed on
+// stderr and returns its index.
+func pickOne(prompt string, n int) (int, error) {
+	fmt.Fprintf(stderr, "%s [1-%d]: ", prompt, n)
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return 0, errors.New("nothing picked")
+	}
+	choice, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || choice < 1 || choice > n {
+		return 0, fmt.Errorf("invalid choice %q, pick a number from 1 to %d", strings.TrimSpace(line), n)
+	}
+	return choice - 1, nil
+}
+
+// pickMany asks the user to pick any of the n numbered choices listed on
+// stderr, as numbers and ranges such as "1 3-4", and returns their indexes
+// in the order given.
+func pickMany(prompt string, n int) ([]int, error) {
+	fmt.Fprintf(stderr, "%s, e.g. 1 or 1,3-4 [1-%d]: ", prompt, n)
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return nil, errors.New("nothing picked")
+	}
+	return parseChoices(line, n)
+}
+
+// parseChoices parses numbers and ranges from 1 to n separated by commas or
+// spaces into indexes, dropping repeated ones.
+func parseChoices(s string, n int) ([]int, error) {
+	var picked []int
+	seen := make(map[int]bool)
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r' })
+	for _, field := range fields {
+		from, to := field, field
+		if i := strings.Index(field, "-"); i > 0 {
+			from, to = field[:i], field[i+1:]
+		}
+		first, err1 := strconv.Atoi(from)
+		last, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || first < 1 || last > n || first > last {
+			return nil, fmt.Errorf("invalid choice %q, pick numbers from 1 to %d", field, n)
+		}
+		for i := first; i <= last; i++ {
+			if !seen[i] {
+				seen[i] = true
+				picked = append(picked, i-1)
+			}
+		}
+	}
+	if len(picked) == 0 {
+		return nil, errors.New("nothing picked")
+	}
+	return picked, nil
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// The oauth2 package always authenticates token requests with the client
+// secret, so the Authorization Code with PKCE flow, where the client only
+// has an ID, makes its own token requests.
+
+// newPKCEVerifier returns a random code verifier.
+func newPKCEVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// pkceChallenge returns the S256 code challenge for verifier.
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// pkceAuthCodeURL returns the URL to the consent page for the PKCE flow.
+func pkceAuthCodeURL(config *oauth2.Config, state, verifier string) string {
+	return config.AuthCodeURL(state,
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+		oauth2.SetAuthURLParam("code_challenge", pkceChallenge(verifier)),
+	)
+}
+
+// pkceExchange exchanges code for a token, proving the possession of
+// verifier instead of the client secret. The token is marked with grantPKCE
+// so it is refreshed the same way.
+func pkceExchange(config *oauth2.Config, code, verifier string) (*oauth2.Token, error) {
+	token, err := retrieveToken(config, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {config.RedirectURL},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return withGrant(token, grantPKCE), nil
+}
+
+// pkceRefresher is an oauth2.TokenSource refreshing tokens obtained with
+// PKCE. Spotify may rotate the refresh token, so it keeps the latest one.
+type pkceRefresher struct {
+	config       *oauth2.Config
+	refreshToken string
+}
+
+// Token implements oauth2.TokenSource.
+func (r *pkceRefresher) Token() (*oauth2.Token, error) {
+	if r.refreshToken == "" {
+		return nil, fmt.Errorf("token expired and refresh token is not set")
+	}
+	token, err := retrieveToken(r.config, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {r.refreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = r.refreshToken
+	}
+	r.refreshToken = token.RefreshToken
+	return token, nil
+}
+
+// retrieveToken posts v, along with the client ID, to the token endpoint.
+func retrieveToken(config *oauth2.Config, v url.Values) (*oauth2.Token, error) {
+	v.Set("client_id", config.ClientID)
+	req, err := http.NewRequest("POST", config.Endpoint.TokenURL, strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpClient.Do(req.WithContext(oauthContext()))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch token: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("cannot fetch token: %s\nResponse: %s", resp.Status, body)
+	}
+
+	var tr struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+		Scope        string `json:"scope"`
+	}
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return nil, fmt.Errorf("cannot fetch token: %v", err)
+	}
+	if tr.AccessToken == "" {
+		return nil, fmt.Errorf("server response missing access_token")
+	}
+	token := &oauth2.Token{
+		AccessToken:  tr.AccessToken,
+		TokenType:    tr.TokenType,
+		RefreshToken: tr.RefreshToken,
+	}
+	if tr.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return token.WithExtra(map[string]interface{}{"scope": tr.Scope}), nil
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestPKCEChallenge(t *testing.T) {
+	// example from RFC 7636, appendix B
+	got := pkceChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("got challenge %q, want %q", got, want)
+	}
+
+	verifier, err := newPKCEVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(verifier); n < 43 || n > 128 {
+		t.Errorf("verifier has %d characters, want 43 to 128", n)
+	}
+}
+
+func TestPKCEExchangeAndRefresh(t *testing.T) {
+	var refreshes int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); ok {
+			t.Error("token request authenticated with a client secret")
+		}
+		if id := r.PostFormValue("client_id"); id != "id" {
+			t.Errorf("got client_id %q, want id", id)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.PostFormValue("grant_type") {
+		case "authorization_code":
+			if v := r.PostFormValue("code_verifier"); v != "verifier" {
+				t.Errorf("got code_verifier %q, want verifier", v)
+			}
+			fmt.Fprint(w, `{"access_token":"access0","token_type":"Bearer","refresh_token":"refresh0","expires_in":3600}`)
+		case "refresh_token":
+			if rt, want := r.PostFormValue("refresh_token"), fmt.Sprintf("refresh%d", refreshes); rt != want {
+				t.Errorf("got refresh_token %q, want %q", rt, want)
+			}
+			refreshes++
+			// tokens expiring within a second count as expired already
+			fmt.Fprintf(w, `{"access_token":"access%d","token_type":"Bearer","refresh_token":"refresh%d","expires_in":1}`, refreshes, refreshes)
+		default:
+			http.Error(w, "unsupported grant type", http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	httpClient = srv.Client()
+	defer func() { httpClient = nil }()
+	config := &oauth2.Config{ClientID: "id", Endpoint: oauth2.Endpoint{TokenURL: srv.URL}}
+
+	token, err := pkceExchange(config, "code", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "access0" || token.RefreshToken != "refresh0" || token.Expiry.IsZero() {
+		t.Fatalf("unexpected token %+v", token)
+	}
+
+	// expired tokens are refreshed with the rotated refresh token, without
+	// the client secret even when one is configured
+	store := &tokenStore{path: t.TempDir() + "/" + tokenFile}
+	token.Expiry = time.Now().Add(-time.Hour)
+	if err := store.Save(token); err != nil {
+		t.Fatal(err)
+	}
+	if token, err = store.Load(); err != nil {
+		t.Fatal(err)
+	}
+	config.ClientSecret = "secret"
+	src := newSavingTokenSource(config, token, store)
+	for i := 1; i <= 2; i++ {
+		got, err := src.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("access%d", i); got.AccessToken != want {
+			t.Errorf("got access token %q, want %q", got.AccessToken, want)
+		}
+	}
+	saved, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.RefreshToken != "refresh2" {
+		t.Errorf("got saved refresh token %q, want refresh2", saved.RefreshToken)
+	}
+	if tokenGrant(saved) != grantPKCE {
+		t.Errorf("got saved grant %q, want %q", tokenGrant(saved), grantPKCE)
+	}
+}
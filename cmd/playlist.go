@@ -1,11 +1,9 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"reflect"
 	"sort"
-	"strconv"
-	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/zmb3/spotify"
@@ -22,15 +20,27 @@ var (
 var (
 	addTrackID                 string
 	addTrackByIDToPlaylistName string
+	addTrackIDsFile            string
 )
 
 var (
 	addTrackName                 string
 	addTrackByNameToPlaylistName string
+	addTrackInteractive          bool
+	addTrackArtist               string
+	addTrackAlbum                string
+	addTrackYear                 string
+	addTracklistFile             string
+	addTracklistReport           string
+	addTracklistMinScore         float64
 )
 
 var (
 	rmTrackName             string
+	rmTrackID               string
+	rmTrackPositions        string
+	rmTrackArtist           string
+	rmTrackAll              bool
 	rmTrackFromPlaylistName string
 )
 
@@ -43,13 +53,16 @@ var (
 )
 
 var (
-	listPlaylistTracksName string
+	listPlaylistTracksName   string
+	listPlaylistTracksLimit  int
+	listPlaylistTracksOffset int
 )
 
 func newCurrentTrackCmd() *cobra.Command {
 	nowCmd := &cobra.Command{
-		Use:   "now",
-		Short: "Displays the currently playing track",
+		Use:         "now",
+		Short:       "Displays the currently playing track",
+		Annotations: needsScopes(spotify.ScopeUserReadCurrentlyPlaying),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return displayCurrentTrack(cmd, args)
 		},
@@ -59,71 +72,97 @@ func newCurrentTrackCmd() *cobra.Command {
 
 func newShowTrackCmd() *cobra.Command {
 	addtoCmd := &cobra.Command{
-		Use:   "show --tid [TRACK_ID]",
-		Short: "Display information about a track by ID",
+		Use:         "show --tid [TRACK_ID]",
+		Short:       "Display information about a track by ID",
+		Annotations: catalogCommand(),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return displayTrackById(cmd, args)
 		},
 	}
-	addtoCmd.Flags().StringVar(&trackID, "tid", "", "Id of track to display.")
+	addtoCmd.Flags().StringVar(&trackID, "tid", "", "ID, URI or URL of track to display.")
+	addtoCmd.Flags().BoolVar(&appOnly, "app-only", false, "Read the track as the application, without a user (default when not logged in and the client secret is set).")
 	return addtoCmd
 }
 
 func newAddtoPlaylistCmd() *cobra.Command {
 	addtoCmd := &cobra.Command{
-		Use:   "ato --p [PLAYLIST_NAME]",
-		Short: "Add currently playing track to playlist",
+		Use:         "ato --p [PLAYLIST_NAME]",
+		Short:       "Add currently playing track to playlist",
+		Annotations: needsScopes(append([]string{spotify.ScopeUserReadCurrentlyPlaying}, playlistModifyScopes...)...),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return addto(cmd, args)
 		},
 	}
-	addtoCmd.Flags().StringVar(&addtoPlaylistName, "p", "", "Add current track to specified playlist.")
+	addtoCmd.Flags().StringVar(&addtoPlaylistName, "p", "", "Add current track to specified playlist (name, ID, URI or URL).")
+	addPlaylistSelectorFlags(addtoCmd.Flags())
+	addAllowDuplicatesFlag(addtoCmd.Flags())
 	return addtoCmd
 }
 
 func newAddTrackByIDToPlaylistCmd() *cobra.Command {
 	addCmd := &cobra.Command{
-		Use:   "aid --tid [TRACK_ID] --p [PLAYLIST_NAME]",
-		Short: "Add track by ID to playlist",
+		Use:         "aid --tid [TRACK_ID] --p [PLAYLIST_NAME]",
+		Short:       "Add track by ID to playlist",
+		Annotations: needsScopes(playlistModifyScopes...),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return addTrackByIDToPlaylist(cmd, args)
 		},
 	}
-	addCmd.Flags().StringVar(&addTrackID, "tid", "", "Id of track to add to playlist.")
-	addCmd.Flags().StringVar(&addTrackByIDToPlaylistName, "p", "", "Name of playlist to add track to.")
+	addCmd.Flags().StringVar(&addTrackID, "tid", "", "ID, URI or URL of track to add to playlist.")
+	addCmd.Flags().StringVar(&addTrackByIDToPlaylistName, "p", "", "Name, ID, URI or URL of playlist to add track to.")
+	addCmd.Flags().StringVar(&addTrackIDsFile, "from-file", "", "Add the tracks listed by ID, URI or URL one per line in this file, - for stdin.")
+	addPlaylistSelectorFlags(addCmd.Flags())
+	addAllowDuplicatesFlag(addCmd.Flags())
 	return addCmd
 }
 
 func newAddTrackByNameToPlaylistCmd() *cobra.Command {
 	addCmd := &cobra.Command{
-		Use:   "add --t [TRACK_NAME] --p [PLAYLIST_NAME]",
-		Short: "Add track by name to playlist",
+		Use:         "add --t [TRACK_NAME] --p [PLAYLIST_NAME]",
+		Short:       "Add track by name to playlist",
+		Annotations: needsScopes(playlistModifyScopes...),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return addTrackByNameToPlaylist(cmd, args)
 		},
 	}
 	addCmd.Flags().StringVar(&addTrackName, "t", "", "Name of track to add to playlist.")
-	addCmd.Flags().StringVar(&addTrackByNameToPlaylistName, "p", "", "Name of playlist to add track to.")
+	addCmd.Flags().StringVar(&addTrackByNameToPlaylistName, "p", "", "Name, ID, URI or URL of playlist to add track to.")
+	addCmd.Flags().BoolVar(&addTrackInteractive, "interactive", false, "List the matching tracks and pick which ones to add.")
+	addCmd.Flags().StringVar(&addTrackArtist, "artist", "", "Only consider tracks by an artist containing this.")
+	addCmd.Flags().StringVar(&addTrackAlbum, "album", "", "Only consider tracks from an album containing this.")
+	addCmd.Flags().StringVar(&addTrackYear, "year", "", "Only consider tracks from an album released this year.")
+	addCmd.Flags().StringVar(&addTracklistFile, "from-file", "", "Add the tracks listed as \"Artist - Title\" one per line in this file, - for stdin.")
+	addCmd.Flags().StringVar(&addTracklistReport, "report", "", "Write the lines of --from-file without a confident match to this file (default FILE.report, stderr for stdin).")
+	addCmd.Flags().Float64Var(&addTracklistMinScore, "min-score", defaultMinScore, "Score from 0 to 1 from which a match of a --from-file line is added.")
+	addPlaylistSelectorFlags(addCmd.Flags())
+	addAllowDuplicatesFlag(addCmd.Flags())
 	return addCmd
 }
 
 func newRemoveTrackFromPlaylistCmd() *cobra.Command {
 	rmCmd := &cobra.Command{
-		Use:   "rm --t [TRACK_NAME] --p [PLAYLIST_NAME]",
-		Short: "Remove track from playlist",
+		Use:         "rm --t [TRACK_NAME] | --tid [TRACK_ID] | --pos [POSITIONS] --p [PLAYLIST_NAME]",
+		Short:       "Remove track from playlist",
+		Annotations: needsScopes(playlistModifyScopes...),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return rmTrackByNameFromPlaylist(cmd, args)
 		},
 	}
 	rmCmd.Flags().StringVar(&rmTrackName, "t", "", "Name of track to remove.")
-	rmCmd.Flags().StringVar(&rmTrackFromPlaylistName, "p", "", "Name of playlist to remove track from.")
+	rmCmd.Flags().StringVar(&rmTrackID, "tid", "", "ID, URI or URL of track to remove.")
+	rmCmd.Flags().StringVar(&rmTrackPositions, "pos", "", "Positions of tracks to remove, from 1, e.g. 3 or 1,4-6.")
+	rmCmd.Flags().StringVar(&rmTrackArtist, "artist", "", "Only remove tracks by an artist containing this.")
+	rmCmd.Flags().BoolVar(&rmTrackAll, "all", false, "Remove every matching occurrence instead of asking which.")
+	rmCmd.Flags().StringVar(&rmTrackFromPlaylistName, "p", "", "Name, ID, URI or URL of playlist to remove track from.")
+	addPlaylistSelectorFlags(rmCmd.Flags())
 	return rmCmd
 }
 
 func newListPlaylistsCmd() *cobra.Command {
 	newCmd := &cobra.Command{
-		Use:   "playlists",
-		Short: "Show all playlists",
+		Use:         "playlists",
+		Short:       "Show all playlists",
+		Annotations: needsScopes(playlistReadScopes...),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return listPlaylists(cmd, args)
 		},
@@ -133,8 +172,9 @@ func newListPlaylistsCmd() *cobra.Command {
 
 func newCreatePlaylistCmd() *cobra.Command {
 	newCmd := &cobra.Command{
-		Use:   "new --p [PLAYLIST_NAME]",
-		Short: "Create new playlist",
+		Use:         "new --p [PLAYLIST_NAME]",
+		Short:       "Create new playlist",
+		Annotations: needsScopes(playlistModifyScopes...),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return newPlaylist(cmd, args)
 		},
@@ -145,66 +185,60 @@ func newCreatePlaylistCmd() *cobra.Command {
 
 func newDeletePlaylistCmd() *cobra.Command {
 	deleteCmd := &cobra.Command{
-		Use:   "del --p [PLAYLIST_NAME]",
-		Short: "Delete a playlist",
+		Use:         "del --p [PLAYLIST_NAME]",
+		Short:       "Delete a playlist",
+		Annotations: needsScopes(playlistModifyScopes...),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return deletePlaylist(cmd, args)
 		},
 	}
-	deleteCmd.Flags().StringVar(&delPlaylistName, "p", "", "Name of playlist to delete.")
+	deleteCmd.Flags().StringVar(&delPlaylistName, "p", "", "Name, ID, URI or URL of playlist to delete.")
+	addPlaylistSelectorFlags(deleteCmd.Flags())
 	return deleteCmd
 }
 
 func newListPlaylistTracksCmd() *cobra.Command {
 	listCmd := &cobra.Command{
-		Use:   "list --p [PLAYLIST_NAME]",
-		Short: "List tracks in playlist",
+		Use:         "list --p [PLAYLIST_NAME]",
+		Short:       "List tracks in playlist",
+		Annotations: needsScopes(playlistReadScopes...),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return listTracksFromPlaylist(cmd, args)
 		},
 	}
-	listCmd.Flags().StringVar(&listPlaylistTracksName, "p", "", "Name of playlist to list tracks from.")
+	listCmd.Flags().StringVar(&listPlaylistTracksName, "p", "", "Name, ID, URI or URL of playlist to list tracks from.")
+	addPlaylistSelectorFlags(listCmd.Flags())
+	listCmd.Flags().IntVar(&listPlaylistTracksLimit, "limit", 0, "Maximum number of tracks to list (0 lists all).")
+	listCmd.Flags().IntVar(&listPlaylistTracksOffset, "offset", 0, "Position of the first track to list.")
 	return listCmd
 }
 
 func displayTrack(track *spotify.FullTrack) error {
-	// format and display
-	var data [][]interface{}
-	item := []string{
-		string(track.ID),
-		track.Name,
-		track.Album.Name,
-		track.Artists[0].Name,
-		(time.Duration(track.Duration) * time.Millisecond).Truncate(time.Second).String(),
-		strconv.Itoa(track.Popularity),
-		strconv.FormatBool(track.Explicit),
-		track.PreviewURL,
-	}
-	row := make([]interface{}, len(item))
-	for i, d := range item {
-		row[i] = d
-	}
-	data = append(data, row)
-	printSimple([]string{"ID", "Name", "Album", "Artist", "Duration", "Popularity", "Explicit", "Preview"}, data)
-	return nil
+	// format and render
+	return render(trackDetailView, []trackRecord{newTrackRecord(*track)})
 }
 
 func displayTrackById(cmd *cobra.Command, args []string) error {
-	// current user
-	user, err := client.CurrentUser()
-	if err != nil {
-		return err
+	// current user, unless there is none
+	if !appOnly {
+		user, err := client.CurrentUser()
+		if err != nil {
+			return err
+		}
+		printInfo("User: ", user.DisplayName)
 	}
-	fmt.Println("User: ", user.DisplayName)
 
 	// get the track (check for existence)
-	track, err := client.GetTrack(spotify.ID(trackID))
+	id, err := resolveTrackID(trackID)
+	if err != nil {
+		return err
+	}
+	track, err := client.GetTrack(id)
 	if err != nil {
 		return err
 	}
 
-  displayTrack(track)
-  return nil
+	return displayTrack(track)
 }
 
 func displayCurrentTrack(cmd *cobra.Command, args []string) error {
@@ -213,16 +247,15 @@ func displayCurrentTrack(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	fmt.Println("User: ", user.DisplayName)
+	printInfo("User: ", user.DisplayName)
 
 	// get current playing song
-	playing, err := client.PlayerCurrentlyPlaying()
+	playing, err := currentTrack()
 	if err != nil {
 		return err
 	}
 
-  displayTrack(playing.Item)
-	return nil
+	return displayTrack(playing)
 }
 
 func addto(cmd *cobra.Command, args []string) error {
@@ -231,29 +264,24 @@ func addto(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	fmt.Println("User: ", user.DisplayName)
+	printInfo("User: ", user.DisplayName)
 
 	// get my playlists
-	pl, err := getPlaylistByName(addtoPlaylistName)
+	pl, err := resolvePlaylist(addtoPlaylistName)
 	if err != nil {
 		return err
 	}
-	fmt.Println("Playlist: ", pl.Name)
+	printInfo("Playlist: ", pl.Name)
 
 	// get current playing song
-	playing, err := client.PlayerCurrentlyPlaying()
+	playing, err := currentTrack()
 	if err != nil {
 		return err
 	}
-	fmt.Println("Track: ", playing.Item.Name)
+	printInfo("Track: ", playing.Name)
 
 	// add track to playlist
-	_, err = client.AddTracksToPlaylist(user.ID, pl.ID, playing.Item.ID)
-	if err != nil {
-		return err
-	}
-	fmt.Printf("Added track \"%s\" to playlist \"%s\".\n", playing.Item.Name, pl.Name)
-	return nil
+	return addTracks(user.ID, pl, []*spotify.FullTrack{playing})
 }
 
 func listPlaylists(cmd *cobra.Command, args []string) error {
@@ -262,7 +290,7 @@ func listPlaylists(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	fmt.Println("User: ", user.DisplayName)
+	printInfo("User: ", user.DisplayName)
 
 	// get all playlists for the user
 	playlists, err := getPlaylists()
@@ -271,26 +299,16 @@ func listPlaylists(cmd *cobra.Command, args []string) error {
 	}
 
 	// format resulting data
-	var data [][]interface{}
-	if playlists.Playlists != nil {
-		for _, item := range playlists.Playlists {
-			track := []string{
-				string(item.ID),
-				item.Name,
-				item.Owner.DisplayName,
-				strconv.FormatBool(item.IsPublic),
-				strconv.FormatBool(item.Collaborative),
-				strconv.FormatUint(uint64(item.Tracks.Total), 10)}
-			row := make([]interface{}, len(track))
-			for i, d := range track {
-				row[i] = d
-			}
-			data = append(data, row)
-		}
+	records := make([]playlistRecord, len(playlists))
+	for i, item := range playlists {
+		records[i] = newPlaylistRecord(item)
 	}
 
-	// pretty print track results
-	printSimple([]string{"ID", "Name", "Owner", "Public", "Collaborative", "Tracks"}, data)
+	// render playlist results
+	if err := render(playlistView, records); err != nil {
+		return err
+	}
+	printInfo("Total: ", len(playlists))
 	return nil
 }
 
@@ -300,14 +318,14 @@ func newPlaylist(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	fmt.Println("User: ", user.DisplayName)
+	printInfo("User: ", user.DisplayName)
 
 	// create new playlist
 	playlist, err := client.CreatePlaylistForUser(user.ID, newPlaylistName, true)
 	if err != nil {
 		return err
 	}
-	fmt.Println("Created public playlist: ", playlist.Name)
+	printInfo("Created public playlist: ", playlist.Name)
 	return nil
 }
 
@@ -317,10 +335,10 @@ func deletePlaylist(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	fmt.Println("User: ", user.DisplayName)
+	printInfo("User: ", user.DisplayName)
 
 	// get the playlist
-	pl, err := getPlaylistByName(delPlaylistName)
+	pl, err := resolvePlaylist(delPlaylistName)
 	if err != nil {
 		return err
 	}
@@ -331,182 +349,261 @@ func deletePlaylist(cmd *cobra.Command, args []string) error {
 }
 
 func addTrackByIDToPlaylist(cmd *cobra.Command, args []string) error {
+	if addTrackIDsFile != "" && addTrackID != "" {
+		return errors.New("give either --tid or --from-file")
+	}
+
 	// current user
 	user, err := client.CurrentUser()
 	if err != nil {
 		return err
 	}
-	fmt.Println("User: ", user.DisplayName)
+	printInfo("User: ", user.DisplayName)
 
-	// get the playlist by name
-	pl, err := getPlaylistByName(addTrackByIDToPlaylistName)
+	// get the playlist
+	pl, err := resolvePlaylist(addTrackByIDToPlaylistName)
 	if err != nil {
 		return err
 	}
-	fmt.Println("Playlist: ", pl.Name)
+	printInfo("Playlist: ", pl.Name)
+
+	// add a whole list at once
+	if addTrackIDsFile != "" {
+		return addTrackIDsFromFile(user, pl, addTrackIDsFile)
+	}
 
 	// get the track (check for existence)
-	tr, err := client.GetTrack(spotify.ID(addTrackID))
+	id, err := resolveTrackID(addTrackID)
 	if err != nil {
 		return err
 	}
-	fmt.Println("Track: ", tr.Name)
-
-	// add track to playlist
-	_, err = client.AddTracksToPlaylist(user.ID, pl.ID, tr.ID)
+	tr, err := client.GetTrack(id)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Added track \"%s\" to playlist \"%s\".\n", tr.Name, pl.Name)
-	return nil
+	printInfo("Track: ", tr.Name)
+
+	// add track to playlist
+	return addTracks(user.ID, pl, []*spotify.FullTrack{tr})
 }
 
 func addTrackByNameToPlaylist(cmd *cobra.Command, args []string) error {
+	if addTracklistFile != "" && addTrackName != "" {
+		return errors.New("give either --t or --from-file")
+	}
+	if addTracklistMinScore < 0 || addTracklistMinScore > 1 {
+		return errors.New("--min-score must be between 0 and 1")
+	}
+
 	// current user
 	user, err := client.CurrentUser()
 	if err != nil {
 		return err
 	}
-	fmt.Println("User: ", user.DisplayName)
+	printInfo("User: ", user.DisplayName)
 
-	// get the playlist by name
-	pl, err := getPlaylistByName(addTrackByNameToPlaylistName)
+	// get the playlist
+	pl, err := resolvePlaylist(addTrackByNameToPlaylistName)
 	if err != nil {
 		return err
 	}
-	fmt.Println("Playlist: ", pl.Name)
+	printInfo("Playlist: ", pl.Name)
+
+	// match a whole list at once
+	if addTracklistFile != "" {
+		report := addTracklistReport
+		if report == "" && addTracklistFile != "-" {
+			report = addTracklistFile + ".report"
+		}
+		return addTracklistFromFile(user, pl, addTracklistFile, report, addTracklistMinScore)
+	}
 
 	// Search for the track
-	results, err := client.Search(addTrackName, spotify.SearchTypeTrack)
+	results, err := searchMarket(addTrackName, spotify.SearchTypeTrack)
 	if err != nil {
 		return err
 	}
 
-	// add most popular track to playlist from results
-	if results.Tracks != nil {
-		tracks := results.Tracks.Tracks[:]
-		sort.Slice(tracks, func(i, j int) bool { return tracks[i].Popularity > tracks[j].Popularity })
-		fmt.Println("Track: ", tracks[0].Name)
+	if results.Tracks == nil || len(results.Tracks.Tracks) == 0 {
+		printInfof("Track %s not found.\n", addTrackName)
+		return nil
+	}
+
+	// rank by popularity and narrow down with the filters
+	tracks := results.Tracks.Tracks[:]
+	sort.SliceStable(tracks, func(i, j int) bool { return tracks[i].Popularity > tracks[j].Popularity })
+	candidates, err := newTrackCandidates(tracks, addTrackInteractive || addTrackYear != "")
+	if err != nil {
+		return err
+	}
+	candidates = filterTrackCandidates(candidates, addTrackArtist, addTrackAlbum, addTrackYear)
+	if len(candidates) == 0 {
+		printInfof("No track %s matches the filters.\n", addTrackName)
+		return nil
+	}
 
-		// add track to playlist
-		_, err = client.AddTracksToPlaylist(user.ID, pl.ID, tracks[0].ID)
+	// the most popular one, unless the user picks
+	picked := candidates[:1]
+	if addTrackInteractive {
+		fmt.Fprintf(stderr, "Tracks matching %q:\n%s", addTrackName, describeTrackCandidates(candidates))
+		choices, err := pickMany("Tracks to add", len(candidates))
 		if err != nil {
 			return err
 		}
-		fmt.Printf("Added track \"%s\" to playlist \"%s\".\n", tracks[0].Name, pl.Name)
-	} else {
-		fmt.Printf("Track %s not found.\n", addTrackName)
+		picked = make([]trackCandidate, len(choices))
+		for i, choice := range choices {
+			picked[i] = candidates[choice]
+		}
 	}
-	return nil
+	chosen := make([]*spotify.FullTrack, len(picked))
+	for i := range picked {
+		printInfo("Track: ", picked[i].track.Name)
+		chosen[i] = &picked[i].track
+	}
+
+	// add tracks to playlist
+	return addTracks(user.ID, pl, chosen)
 }
 
 func rmTrackByNameFromPlaylist(cmd *cobra.Command, args []string) error {
+	if rmTrackName == "" && rmTrackID == "" && rmTrackPositions == "" {
+		return errors.New("give --t, --tid or --pos")
+	}
+	if rmTrackName != "" && rmTrackID != "" {
+		return errors.New("give either --t or --tid")
+	}
+	var id spotify.ID
+	if rmTrackID != "" {
+		var err error
+		if id, err = resolveTrackID(rmTrackID); err != nil {
+			return err
+		}
+	}
+
 	// current user
 	user, err := client.CurrentUser()
 	if err != nil {
 		return err
 	}
-	fmt.Println("User: ", user.DisplayName)
+	printInfo("User: ", user.DisplayName)
 
-	// get the playlist by name
-	pl, err := getPlaylistByName(rmTrackFromPlaylistName)
+	// get the playlist
+	pl, err := resolvePlaylist(rmTrackFromPlaylistName)
 	if err != nil {
 		return err
 	}
 
-	// get track in playlist and validate existence
-	var matchedTrack spotify.SimpleTrack
-	ptracks, err := client.GetPlaylistTracks(user.ID, pl.ID)
-	for _, t := range ptracks.Tracks {
-		if rmTrackName == t.Track.SimpleTrack.Name {
-			matchedTrack = t.Track.SimpleTrack
-			break
+	// find the occurrences to remove
+	var positions map[int]bool
+	if rmTrackPositions != "" {
+		if positions, err = parsePositions(rmTrackPositions, int(pl.Tracks.Total)); err != nil {
+			return err
 		}
 	}
-	if reflect.DeepEqual(matchedTrack, spotify.SimpleTrack{}) {
-		return fmt.Errorf("track %s not found in playlist %s", rmTrackName, rmTrackFromPlaylistName)
+	matched, err := findPlaylistEntries(user.ID, pl.ID, positions, id, rmTrackName, rmTrackArtist)
+	if err != nil {
+		return err
+	}
+	if len(matched) == 0 {
+		return fmt.Errorf("no track in playlist %s matches", pl.Name)
 	}
-	fmt.Println("Track: ", matchedTrack.Name)
 
-	// remove track from playlist
-	_, err = client.RemoveTracksFromPlaylist(user.ID, pl.ID, matchedTrack.ID)
+	// several occurrences, of different songs sharing the name or of the
+	// same track, unless picked by position or all wanted
+	if rmTrackPositions == "" && !rmTrackAll && len(matched) > 1 {
+		ref := rmTrackName
+		if ref == "" {
+			ref = rmTrackID
+		}
+		if !isInteractive() {
+			if distinctTracks(matched) > 1 {
+				return fmt.Errorf("track %s is ambiguous, %d tracks match:\n%spick with --pos N, --artist ARTIST or --tid ID, or remove them all with --all",
+					ref, len(matched), describePlaylistEntries(matched))
+			}
+			return fmt.Errorf("track %s appears %d times:\n%spick with --pos N, or remove them all with --all",
+				ref, len(matched), describePlaylistEntries(matched))
+		}
+		fmt.Fprintf(stderr, "%d tracks match %q:\n%s", len(matched), ref, describePlaylistEntries(matched))
+		picked, err := pickMany("Tracks to remove", len(matched))
+		if err != nil {
+			return err
+		}
+		var chosen []playlistEntry
+		for _, i := range picked {
+			chosen = append(chosen, matched[i])
+		}
+		matched = chosen
+	}
+
+	// remove exactly those occurrences
+	matched, err = removePlaylistEntries(user.ID, pl, matched)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Removed track \"%s\" from playlist \"%s\".\n", matchedTrack.Name, rmTrackFromPlaylistName)
+	if len(matched) == 1 {
+		printInfof("Removed track \"%s\" from playlist \"%s\".\n", matched[0].track.Name, pl.Name)
+	} else {
+		printInfof("Removed %d tracks from playlist \"%s\".\n", len(matched), pl.Name)
+	}
 	return nil
 }
 
 func listTracksFromPlaylist(cmd *cobra.Command, args []string) error {
+	if listPlaylistTracksLimit < 0 || listPlaylistTracksOffset < 0 {
+		return errors.New("limit and offset must not be negative")
+	}
+
 	// current user
 	user, err := client.CurrentUser()
 	if err != nil {
 		return err
 	}
-	fmt.Println("User: ", user.DisplayName)
+	printInfo("User: ", user.DisplayName)
 
-	pl, err := getPlaylistByName(listPlaylistTracksName)
+	pl, err := resolvePlaylist(listPlaylistTracksName)
 	if err != nil {
 		return err
 	}
 
-	// get tracks from playlist
-	tracks, err := client.GetPlaylistTracks(user.ID, pl.ID)
+	// render tracks from playlist as each page comes
+	w, err := newRecordWriter(trackView)
 	if err != nil {
 		return err
 	}
-
-	// format resulting data
-	var data [][]interface{}
-	if tracks.Tracks != nil {
-		for _, item := range tracks.Tracks {
-			track := []string{
-				string(item.Track.ID),
-				item.Track.Name,
-				item.Track.Album.Name,
-				item.Track.Artists[0].Name,
-				strconv.Itoa(item.Track.Popularity)}
-			row := make([]interface{}, len(track))
-			for i, d := range track {
-				row[i] = d
-			}
-			data = append(data, row)
+	it := newPlaylistTrackIterator(user.ID, pl.ID, listPlaylistTracksOffset, listPlaylistTracksLimit)
+	for it.Next() {
+		if err := w.Write(newTrackRecord(it.Track().Track)); err != nil {
+			return err
 		}
 	}
-
-	// pretty print track results
-	printSimple([]string{"ID", "Name", "Album", "Artist", "Popularity"}, data)
-	return nil
-}
-
-func getPlaylists() (*spotify.SimplePlaylistPage, error) {
-	playlists, err := client.CurrentUsersPlaylists()
-	if err != nil {
-		return &(spotify.SimplePlaylistPage{}), err
+	if err := it.Err(); err != nil {
+		return err
 	}
-
-  return playlists, nil
+	return w.Close()
 }
 
-func getPlaylistByName(playlistName string) (spotify.SimplePlaylist, error) {
-	// get current user's playlists
-	playlists, err := client.CurrentUsersPlaylists()
+// currentTrack returns the track currently playing for the user.
+func currentTrack() (*spotify.FullTrack, error) {
+	playing, err := client.PlayerCurrentlyPlaying()
 	if err != nil {
-		return spotify.SimplePlaylist{}, err
+		return nil, err
 	}
-
-	// match by name
-	var matchPlaylist spotify.SimplePlaylist
-	for _, p := range playlists.Playlists {
-		if playlistName == p.Name {
-			matchPlaylist = p
-			break
-		}
+	// the Web API answers with no content when nothing is playing
+	if playing.Item == nil {
+		return nil, errors.New("no track is currently playing")
 	}
+	return playing.Item, nil
+}
 
-	// check if found and return
-	if reflect.DeepEqual(matchPlaylist, spotify.SimplePlaylist{}) {
-		return spotify.SimplePlaylist{}, fmt.Errorf("playlist not found: %s", playlistName)
+func getPlaylists() ([]spotify.SimplePlaylist, error) {
+	// page through the whole library
+	var playlists []spotify.SimplePlaylist
+	it := newPlaylistIterator()
+	for it.Next() {
+		playlists = append(playlists, it.Playlist())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
 	}
-	return matchPlaylist, nil
+	return playlists, nil
 }
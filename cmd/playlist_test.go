@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zmb3/spotify"
+)
+
+func TestListPlaylistsPagesThroughLibrary(t *testing.T) {
+	srv := newTestServer(t)
+	srv.PageSize = 1
+	srv.AddPlaylist(spotify.SimplePlaylist{Name: "Third"})
+
+	out, err := execute(t, srv, "playlists")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Mix", "Empty", "Third"} {
+		if !strings.Contains(out, name) {
+			t.Errorf("playlist %q missing from output:\n%s", name, out)
+		}
+	}
+	if !strings.Contains(out, "Total:  3") {
+		t.Errorf("expected total count in output:\n%s", out)
+	}
+}
+
+func TestGetPlaylistByNameBeyondFirstPage(t *testing.T) {
+	srv := newTestServer(t)
+	srv.PageSize = 1
+	srv.AddPlaylist(spotify.SimplePlaylist{Name: "Last"}, "t300000000000000000000")
+
+	out, err := execute(t, srv, "list", "--p", "Last")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Numb - Live") {
+		t.Errorf("expected track of last playlist in output:\n%s", out)
+	}
+
+	if _, err := execute(t, srv, "list", "--p", "Missing"); err == nil || err.Error() != "playlist not found: Missing" {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestListTracksPagesThroughPlaylist(t *testing.T) {
+	srv := newTestServer(t)
+	srv.PageSize = 1
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{}, []string{"t100000000000000000000", "t200000000000000000000"}},
+		{[]string{"--limit", "1"}, []string{"t100000000000000000000"}},
+		{[]string{"--offset", "1"}, []string{"t200000000000000000000"}},
+		{[]string{"--offset", "2"}, []string{}},
+	}
+	for _, test := range tests {
+		out, err := execute(t, srv, append([]string{"list", "--p", "Mix", "-o", "json"}, test.args...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var records []trackRecord
+		if err := json.Unmarshal([]byte(out), &records); err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, r := range records {
+			ids = append(ids, r.ID)
+		}
+		if !reflect.DeepEqual(ids, test.want) {
+			t.Errorf("list %v: got %v, want %v", test.args, ids, test.want)
+		}
+	}
+
+	if _, err := execute(t, srv, "list", "--p", "Mix", "--limit", "-1"); err == nil {
+		t.Error("expected error for negative limit")
+	}
+}
+
+func TestRemoveTrackBeyondFirstPage(t *testing.T) {
+	srv := newTestServer(t)
+	srv.PageSize = 1
+	id := srv.Playlists()[0].ID
+
+	if _, err := execute(t, srv, "rm", "--t", "Faint", "--p", "Mix"); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.PlaylistTracks(id); !reflect.DeepEqual(got, []spotify.ID{"t100000000000000000000"}) {
+		t.Errorf("got tracks %v after removal", got)
+	}
+
+	if _, err := execute(t, srv, "rm", "--t", "Faint", "--p", "Mix"); err == nil {
+		t.Error("expected error removing missing track")
+	}
+}
+
+func TestRemoveTrackOccurrences(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddTrack(testTrack("t400000000000000000000", "Numb", "U2", "Zooropa", 260000, 50))
+	isInteractive = func() bool { return false }
+	defer func() { isInteractive = origIsInteractive }()
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []spotify.ID
+		wantErr bool
+	}{
+		{"position", []string{"--pos", "3"}, []spotify.ID{"t100000000000000000000", "t200000000000000000000", "t400000000000000000000", "t100000000000000000000"}, false},
+		{"positions", []string{"--pos", "1,3-4"}, []spotify.ID{"t200000000000000000000", "t100000000000000000000"}, false},
+		{"track ID", []string{"--tid", "spotify:track:t100000000000000000000", "--all"}, []spotify.ID{"t200000000000000000000", "t400000000000000000000"}, false},
+		{"track ID at position", []string{"--tid", "t100000000000000000000", "--pos", "3"}, []spotify.ID{"t100000000000000000000", "t200000000000000000000", "t400000000000000000000", "t100000000000000000000"}, false},
+		{"duplicates of a track ID", []string{"--tid", "t100000000000000000000"}, nil, true},
+		{"duplicates of a name", []string{"--t", "Numb", "--artist", "linkin"}, nil, true},
+		{"name with artist", []string{"--t", "Numb", "--artist", "u2"}, []spotify.ID{"t100000000000000000000", "t200000000000000000000", "t100000000000000000000", "t100000000000000000000"}, false},
+		{"name at position", []string{"--t", "Numb", "--pos", "4-5"}, []spotify.ID{"t100000000000000000000", "t200000000000000000000", "t100000000000000000000"}, false},
+		{"ambiguous name", []string{"--t", "Numb"}, nil, true},
+		{"position out of range", []string{"--pos", "6"}, nil, true},
+		{"no track", nil, nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id := srv.AddPlaylist(spotify.SimplePlaylist{Name: test.name}, "t100000000000000000000", "t200000000000000000000", "t100000000000000000000", "t400000000000000000000", "t100000000000000000000")
+			_, err := execute(t, srv, append([]string{"rm", "--p", test.name}, test.args...)...)
+			if test.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := srv.PlaylistTracks(id); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got tracks %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	// the occurrences of a duplicated track are listed with their positions
+	srv.AddPlaylist(spotify.SimplePlaylist{Name: "Dupes"}, "t100000000000000000000", "t200000000000000000000", "t100000000000000000000")
+	_, err := execute(t, srv, "rm", "--p", "Dupes", "--tid", "t100000000000000000000")
+	if err == nil || !strings.Contains(err.Error(), "appears 2 times") || !strings.Contains(err.Error(), "position: 3") {
+		t.Errorf("got %v, want an error listing both positions", err)
+	}
+
+	// local files have no ID to remove them by
+	srv.AddTrack(spotify.FullTrack{SimpleTrack: spotify.SimpleTrack{Name: "Demo", URI: "spotify:local:::Demo:180"}})
+	id := srv.AddPlaylist(spotify.SimplePlaylist{Name: "Locals"}, "t100000000000000000000", "")
+	if _, err := execute(t, srv, "rm", "--p", "Locals", "--pos", "2"); err == nil || !strings.Contains(err.Error(), "local files") {
+		t.Errorf("got %v, want an error about local files", err)
+	}
+	if _, err := execute(t, srv, "rm", "--p", "Locals", "--pos", "1-2"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := srv.PlaylistTracks(id), []spotify.ID{""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got tracks %v, want only the local file left", got)
+	}
+}
+
+func TestAddTracks(t *testing.T) {
+	srv := newTestServer(t)
+	srv.SetPlaying("t300000000000000000000")
+	id := srv.Playlists()[1].ID
+
+	if _, err := execute(t, srv, "ato", "--p", "Empty"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := execute(t, srv, "aid", "--tid", "t200000000000000000000", "--p", "Empty"); err != nil {
+		t.Fatal(err)
+	}
+	// the most popular match wins
+	if _, err := execute(t, srv, "add", "--t", "numb", "--p", "Empty"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []spotify.ID{"t300000000000000000000", "t200000000000000000000", "t100000000000000000000"}
+	if got := srv.PlaylistTracks(id); !reflect.DeepEqual(got, want) {
+		t.Errorf("got tracks %v, want %v", got, want)
+	}
+}
+
+func TestAddSkipsPresentTracks(t *testing.T) {
+	srv := newTestServer(t)
+	srv.PageSize = 1
+	srv.SetPlaying("t200000000000000000000")
+	id := srv.Playlists()[0].ID
+
+	// Mix already holds t1 and t2
+	for _, args := range [][]string{
+		{"ato", "--p", "Mix"},
+		{"aid", "--tid", "t100000000000000000000", "--p", "Mix"},
+		{"add", "--t", "faint", "--p", "Mix"},
+	} {
+		if _, err := execute(t, srv, args...); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+	if got := srv.PlaylistTracks(id); !reflect.DeepEqual(got, []spotify.ID{"t100000000000000000000", "t200000000000000000000"}) {
+		t.Errorf("got tracks %v, want them unchanged", got)
+	}
+
+	if _, err := execute(t, srv, "ato", "--p", "Mix", "--allow-duplicates"); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.PlaylistTracks(id); !reflect.DeepEqual(got, []spotify.ID{"t100000000000000000000", "t200000000000000000000", "t200000000000000000000"}) {
+		t.Errorf("got tracks %v, want t2 added again", got)
+	}
+}
+
+func TestAddTrackCandidates(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		input string
+		want  []spotify.ID
+	}{
+		{"album filter", []string{"--album", "revolution"}, "", []spotify.ID{"t300000000000000000000"}},
+		{"year filter", []string{"--year", "2008"}, "", []spotify.ID{"t300000000000000000000"}},
+		{"artist filter", []string{"--artist", "linkin"}, "", []spotify.ID{"t100000000000000000000"}},
+		{"no match", []string{"--artist", "nobody"}, "", []spotify.ID{}},
+		{"pick one", []string{"--interactive"}, "2\n", []spotify.ID{"t300000000000000000000"}},
+		{"pick several", []string{"--interactive"}, "2, 1\n", []spotify.ID{"t300000000000000000000", "t100000000000000000000"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := newTestServer(t)
+			id := srv.AddPlaylist(spotify.SimplePlaylist{Name: "New"})
+			stdin = strings.NewReader(test.input)
+			defer func() { stdin = origStdin }()
+
+			args := append([]string{"add", "--t", "numb", "--p", "New"}, test.args...)
+			if _, err := execute(t, srv, args...); err != nil {
+				t.Fatal(err)
+			}
+			if got := srv.PlaylistTracks(id); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got tracks %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseChoices(t *testing.T) {
+	got, err := parseChoices("3, 1-2 2\n", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2, 0, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, s := range []string{"", "0", "5", "3-1", "x"} {
+		if _, err := parseChoices(s, 4); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestCreateAndDeletePlaylist(t *testing.T) {
+	srv := newTestServer(t)
+
+	if _, err := execute(t, srv, "new", "--p", "Fresh"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.Playlists()); n != 3 {
+		t.Fatalf("got %d playlists after create", n)
+	}
+	if _, err := execute(t, srv, "del", "--p", "Fresh"); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range srv.Playlists() {
+		if p.Name == "Fresh" {
+			t.Error("playlist still present after delete")
+		}
+	}
+}
+
+func TestDisplayTracks(t *testing.T) {
+	srv := newTestServer(t)
+
+	out, err := execute(t, srv, "show", "--tid", "t200000000000000000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Faint") || !strings.Contains(out, "2m42s") {
+		t.Errorf("unexpected show output:\n%s", out)
+	}
+
+	if _, err := execute(t, srv, "now"); err == nil || err.Error() != "no track is currently playing" {
+		t.Errorf("expected nothing playing error, got %v", err)
+	}
+	srv.SetPlaying("t100000000000000000000")
+	out, err = execute(t, srv, "now")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Numb") {
+		t.Errorf("unexpected now output:\n%s", out)
+	}
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// profile add flags
+	profileClientID     string
+	profileClientSecret string
+	profileMarket       string
+)
+
+func newProfileCmd() *cobra.Command {
+	profileCmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage profiles, each with its own credentials, token and market",
+	}
+	profileCmd.AddCommand(newListProfilesCmd())
+	profileCmd.AddCommand(newAddProfileCmd())
+	profileCmd.AddCommand(newUseProfileCmd())
+	profileCmd.AddCommand(newRemoveProfileCmd())
+	return profileCmd
+}
+
+func newListProfilesCmd() *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List profiles",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listProfiles(cmd, args)
+		},
+	}
+	return listCmd
+}
+
+func newAddProfileCmd() *cobra.Command {
+	addCmd := &cobra.Command{
+		Use:   "add NAME",
+		Short: "Add a profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return addProfile(cmd, args)
+		},
+	}
+	addCmd.Flags().StringVar(&profileClientID, "client-id", "", "Client ID of the Spotify application (default $SPOTIFY_ID)")
+	addCmd.Flags().StringVar(&profileClientSecret, "client-secret", "", "Client secret of the Spotify application (default $SPOTIFY_SECRET)")
+	addCmd.Flags().StringVar(&profileMarket, "market", "", "Country code to search in, e.g. US")
+	return addCmd
+}
+
+func newUseProfileCmd() *cobra.Command {
+	useCmd := &cobra.Command{
+		Use:   "use NAME",
+		Short: "Select the profile used when --profile is not given",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return useProfile(cmd, args)
+		},
+	}
+	return useCmd
+}
+
+func newRemoveProfileCmd() *cobra.Command {
+	removeCmd := &cobra.Command{
+		Use:   "remove NAME",
+		Short: "Remove a profile and its token",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return removeProfile(cmd, args)
+		},
+	}
+	return removeCmd
+}
+
+func listProfiles(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	current := cfg.selectedProfile()
+	var records []profileRecord
+	for _, name := range cfg.profileNames() {
+		p, err := cfg.lookupProfile(name)
+		if err != nil {
+			return err
+		}
+		// listing only looks for the token, newTokenStore would move a legacy one
+		path, err := tokenPath(name)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stat(path)
+		records = append(records, profileRecord{
+			Name:     name,
+			Current:  name == current,
+			ClientID: p.ClientID,
+			Market:   p.Market,
+			LoggedIn: err == nil,
+		})
+	}
+	return render(profileView, records)
+}
+
+func addProfile(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := checkProfileName(name); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if _, err := cfg.lookupProfile(name); err == nil {
+		return fmt.Errorf("profile already exists: %s", name)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]profile)
+	}
+	cfg.Profiles[name] = profile{
+		ClientID:     profileClientID,
+		ClientSecret: profileClientSecret,
+		Market:       profileMarket,
+	}
+	if err := saveConfig(cfg); err != nil {
+		return err
+	}
+	printInfof("Added profile \"%s\", log in with: spotifycli login --profile %s\n", name, name)
+	return nil
+}
+
+func useProfile(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if _, err := cfg.lookupProfile(name); err != nil {
+		return err
+	}
+
+	cfg.Profile = name
+	if name == defaultProfile {
+		cfg.Profile = ""
+	}
+	if err := saveConfig(cfg); err != nil {
+		return err
+	}
+	printInfof("Using profile \"%s\".\n", name)
+	return nil
+}
+
+func removeProfile(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if name == defaultProfile {
+		return errors.New("the default profile cannot be removed")
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if _, err := cfg.lookupProfile(name); err != nil {
+		return err
+	}
+
+	delete(cfg.Profiles, name)
+	if cfg.Profile == name {
+		cfg.Profile = ""
+	}
+	if err := saveConfig(cfg); err != nil {
+		return err
+	}
+
+	// the token lives in a directory of its own
+	path, err := tokenPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(filepath.Dir(path)); err != nil {
+		return err
+	}
+	printInfof("Removed profile \"%s\".\n", name)
+	return nil
+}
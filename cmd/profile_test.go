@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileCommands(t *testing.T) {
+	home := setConfigHome(t)
+	srv := newTestServer(t)
+
+	if _, err := execute(t, srv, "profile", "add", "team", "--client-id", "team-id", "--market", "SE"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := execute(t, srv, "profile", "add", "team"); err == nil {
+		t.Error("expected an error adding an existing profile")
+	}
+	if _, err := execute(t, srv, "profile", "add", "../team"); err == nil {
+		t.Error("expected an error for an invalid profile name")
+	}
+	if _, err := execute(t, srv, "profile", "use", "team"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a token for the team profile is kept apart from the default one
+	store, err := newTokenStore("team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir := filepath.Join(home, appName, "profiles", "team"); filepath.Dir(store.path) != dir {
+		t.Errorf("got token path %s, want it in %s", store.path, dir)
+	}
+	if err := writeFileAtomic(store.path, []byte(`{"access_token":"team"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := execute(t, srv, "profile", "list", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []profileRecord
+	if err := json.Unmarshal([]byte(out), &records); err != nil {
+		t.Fatal(err)
+	}
+	want := []profileRecord{
+		{Name: "default", ClientID: os.Getenv("SPOTIFY_ID")},
+		{Name: "team", Current: true, ClientID: "team-id", Market: "SE", LoggedIn: true},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d profiles, want %d", len(records), len(want))
+	}
+	for i := range want {
+		if records[i] != want[i] {
+			t.Errorf("got profile %+v, want %+v", records[i], want[i])
+		}
+	}
+
+	if _, err := execute(t, srv, "profile", "remove", "default"); err == nil {
+		t.Error("expected an error removing the default profile")
+	}
+	if _, err := execute(t, srv, "profile", "remove", "team"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(store.path); !os.IsNotExist(err) {
+		t.Errorf("expected the token of the removed profile to be deleted, got %v", err)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := cfg.selectedProfile(); name != defaultProfile {
+		t.Errorf("got selected profile %q after removing it, want %q", name, defaultProfile)
+	}
+}
+
+func TestSelectedProfile(t *testing.T) {
+	cfg := &config{Profile: "used"}
+	if name := cfg.selectedProfile(); name != "used" {
+		t.Errorf("got %q, want the profile selected with profile use", name)
+	}
+
+	os.Setenv(profileEnv, "env")
+	defer os.Unsetenv(profileEnv)
+	if name := cfg.selectedProfile(); name != "env" {
+		t.Errorf("got %q, want the profile from %s", name, profileEnv)
+	}
+
+	profileName = "flag"
+	defer func() { profileName = "" }()
+	if name := cfg.selectedProfile(); name != "flag" {
+		t.Errorf("got %q, want the profile from --profile", name)
+	}
+}
+
+func TestProfileNameFromConfigIsChecked(t *testing.T) {
+	home := setConfigHome(t)
+	srv := newTestServer(t)
+
+	// a hand-edited config naming a profile outside the profiles directory
+	outside := filepath.Join(home, appName, "keep")
+	if err := os.MkdirAll(outside, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveConfig(&config{Profiles: map[string]profile{"../../keep": {ClientID: "id"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := execute(t, srv, "profile", "remove", "../../keep"); err == nil {
+		t.Error("expected an error removing a profile with an invalid name")
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("expected %s to be left alone, got %v", outside, err)
+	}
+	if _, err := newTokenStore("../../keep"); err == nil {
+		t.Error("expected an error for the token store of an invalid profile name")
+	}
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/zmb3/spotify"
+)
+
+// trackRecord is the output representation of a track.
+type trackRecord struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Album      string   `json:"album"`
+	Artist     string   `json:"artist"`
+	Artists    []string `json:"artists"`
+	Duration   string   `json:"duration"`
+	DurationMs int      `json:"duration_ms"`
+	Popularity int      `json:"popularity"`
+	Explicit   bool     `json:"explicit"`
+	PreviewURL string   `json:"preview_url"`
+	URI        string   `json:"uri"`
+}
+
+// albumRecord is the output representation of an album.
+type albumRecord struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Artist   string   `json:"artist"`
+	Artists  []string `json:"artists"`
+	Type     string   `json:"type"`
+	Endpoint string   `json:"endpoint"`
+	URI      string   `json:"uri"`
+}
+
+// artistRecord is the output representation of an artist.
+type artistRecord struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Genres     []string `json:"genres"`
+	Followers  int      `json:"followers"`
+	Popularity int      `json:"popularity"`
+	Endpoint   string   `json:"endpoint"`
+	URI        string   `json:"uri"`
+}
+
+// playlistRecord is the output representation of a playlist.
+type playlistRecord struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Owner         string `json:"owner"`
+	OwnerID       string `json:"owner_id"`
+	Public        bool   `json:"public"`
+	Collaborative bool   `json:"collaborative"`
+	Tracks        int    `json:"tracks"`
+	Endpoint      string `json:"endpoint"`
+	URI           string `json:"uri"`
+}
+
+// profileRecord is the output representation of a profile.
+type profileRecord struct {
+	Name     string `json:"name"`
+	Current  bool   `json:"current"`
+	ClientID string `json:"client_id"`
+	Market   string `json:"market"`
+	LoggedIn bool   `json:"logged_in"`
+}
+
+// authStatusRecord is the output representation of the authentication
+// state.
+type authStatusRecord struct {
+	Profile      string   `json:"profile"`
+	TokenFile    string   `json:"token_file"`
+	User         string   `json:"user"`
+	UserID       string   `json:"user_id"`
+	Expiry       string   `json:"expiry"`
+	Expired      bool     `json:"expired"`
+	RefreshToken bool     `json:"refresh_token"`
+	Scopes       []string `json:"scopes"`
+}
+
+var (
+	trackView = view{
+		headers: []string{"ID", "Name", "Album", "Artist", "Popularity"},
+		fields:  []string{"ID", "Name", "Album", "Artist", "Popularity"},
+	}
+	trackDetailView = view{
+		headers: []string{"ID", "Name", "Album", "Artist", "Duration", "Popularity", "Explicit", "Preview"},
+		fields:  []string{"ID", "Name", "Album", "Artist", "Duration", "Popularity", "Explicit", "PreviewURL"},
+	}
+	albumView = view{
+		headers: []string{"ID", "Name", "Artist", "Type", "Endpoint"},
+		fields:  []string{"ID", "Name", "Artist", "Type", "Endpoint"},
+	}
+	artistView = view{
+		headers: []string{"ID", "Name", "Genres", "Followers", "Endpoint"},
+		fields:  []string{"ID", "Name", "Genres", "Followers", "Endpoint"},
+	}
+	playlistView = view{
+		headers: []string{"ID", "Name", "Owner", "Public", "Collaborative", "Tracks"},
+		fields:  []string{"ID", "Name", "Owner", "Public", "Collaborative", "Tracks"},
+	}
+	playlistSearchView = view{
+		headers: []string{"ID", "Name", "Owner", "Total Tracks", "Endpoint"},
+		fields:  []string{"ID", "Name", "Owner", "Tracks", "Endpoint"},
+	}
+	authStatusView = view{
+		headers: []string{"Profile", "User", "Expiry", "Refresh Token", "Scopes", "Token File"},
+		fields:  []string{"Profile", "User", "Expiry", "RefreshToken", "Scopes", "TokenFile"},
+	}
+	profileView = view{
+		headers: []string{"Name", "Current", "Client ID", "Market", "Logged In"},
+		fields:  []string{"Name", "Current", "ClientID", "Market", "LoggedIn"},
+	}
+)
+
+func newTrackRecord(t spotify.FullTrack) trackRecord {
+	artists := artistNames(t.Artists)
+	return trackRecord{
+		ID:         string(t.ID),
+		Name:       t.Name,
+		Album:      t.Album.Name,
+		Artist:     firstOf(artists),
+		Artists:    artists,
+		Duration:   (time.Duration(t.Duration) * time.Millisecond).Truncate(time.Second).String(),
+		DurationMs: t.Duration,
+		Popularity: t.Popularity,
+		Explicit:   t.Explicit,
+		PreviewURL: t.PreviewURL,
+		URI:        string(t.URI),
+	}
+}
+
+func newAlbumRecord(a spotify.SimpleAlbum) albumRecord {
+	artists := artistNames(a.Artists)
+	return albumRecord{
+		ID:       string(a.ID),
+		Name:     a.Name,
+		Artist:   firstOf(artists),
+		Artists:  artists,
+		Type:     a.AlbumType,
+		Endpoint: a.Endpoint,
+		URI:      string(a.URI),
+	}
+}
+
+func newArtistRecord(a spotify.FullArtist) artistRecord {
+	genres := a.Genres
+	if genres == nil {
+		genres = []string{}
+	}
+	return artistRecord{
+		ID:         string(a.ID),
+		Name:       a.Name,
+		Genres:     genres,
+		Followers:  int(a.Followers.Count),
+		Popularity: a.Popularity,
+		Endpoint:   a.Endpoint,
+		URI:        string(a.URI),
+	}
+}
+
+func newPlaylistRecord(p spotify.SimplePlaylist) playlistRecord {
+	return playlistRecord{
+		ID:            string(p.ID),
+		Name:          p.Name,
+		Owner:         p.Owner.DisplayName,
+		OwnerID:       p.Owner.ID,
+		Public:        p.IsPublic,
+		Collaborative: p.Collaborative,
+		Tracks:        int(p.Tracks.Total),
+		Endpoint:      p.Endpoint,
+		URI:           string(p.URI),
+	}
+}
+
+func artistNames(artists []spotify.SimpleArtist) []string {
+	names := make([]string, len(artists))
+	for i, a := range artists {
+		names[i] = a.Name
+	}
+	return names
+}
+
+func firstOf(s []string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	return s[0]
+}
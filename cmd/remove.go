@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zmb3/spotify"
+)
+
+// removeTracksLimit is the maximum number of tracks removed from a playlist
+// in one request.
+const removeTracksLimit = 100
+
+// playlistEntry is an occurrence of a track in a playlist, at a 0-based
+// position.
+type playlistEntry struct {
+	position int
+	track    spotify.FullTrack
+	addedAt  string
+}
+
+// readPlaylistEntries returns every track of the playlist with its position.
+func readPlaylistEntries(userID string, playlistID spotify.ID) ([]playlistEntry, error) {
+	var entries []playlistEntry
+	it := newPlaylistTrackIterator(userID, playlistID, 0, 0)
+	for it.Next() {
+		t := it.Track()
+		entries = append(entries, playlistEntry{position: len(entries), track: t.Track, addedAt: t.AddedAt})
+	}
+	return entries, it.Err()
+}
+
+// findPlaylistEntries walks the playlist and returns the occurrences of the
+// tracks that are id, or named name, by an artist containing artist. Empty
+// criteria match any track. When positions is not nil, only the tracks at
+// those 0-based positions are considered. Only what describing and removing
+// them takes is kept of the matching tracks, so memory doesn't grow with the
+// playlist.
+func findPlaylistEntries(userID string, playlistID spotify.ID, positions map[int]bool, id spotify.ID, name, artist string) ([]playlistEntry, error) {
+	var matched []playlistEntry
+	it := newPlaylistTrackIterator(userID, playlistID, 0, 0)
+	for it.Next() {
+		pos, t := it.Position(), it.Track().Track
+		if positions != nil && !positions[pos] {
+			continue
+		}
+		if id != "" && t.ID != id || name != "" && t.Name != name {
+			continue
+		}
+		if artist != "" && !anyContainsFold(artistNames(t.Artists), artist) {
+			continue
+		}
+		var kept spotify.FullTrack
+		kept.ID, kept.Name, kept.Artists, kept.Album.Name = t.ID, t.Name, t.Artists, t.Album.Name
+		matched = append(matched, playlistEntry{position: pos, track: kept})
+	}
+	return matched, it.Err()
+}
+
+// distinctTracks counts the different tracks among entries.
+func distinctTracks(entries []playlistEntry) int {
+	seen := make(map[spotify.ID]bool)
+	for _, e := range entries {
+		seen[e.track.ID] = true
+	}
+	return len(seen)
+}
+
+// removePlaylistEntries removes exactly the given occurrences from the
+// playlist, leaving other occurrences of the same tracks in place, and
+// returns those it removed. Positions refer to the snapshot pl was read at.
+// Local files have no ID to remove them by, so they are skipped.
+func removePlaylistEntries(userID string, pl spotify.SimplePlaylist, entries []playlistEntry) ([]playlistEntry, error) {
+	var removable []playlistEntry
+	for _, e := range entries {
+		if e.track.ID == "" {
+			fmt.Fprintf(stderr, "Warning: skipping local file \"%s\" at position %d, it can't be removed through the Web API.\n", e.track.Name, e.position+1)
+			continue
+		}
+		removable = append(removable, e)
+	}
+	if len(removable) == 0 {
+		return nil, errors.New("no track to remove, local files can't be removed through the Web API")
+	}
+
+	// the last positions first, so the earlier ones stay put between
+	// requests
+	entries = append([]playlistEntry(nil), removable...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].position > entries[j].position })
+
+	snapshot := pl.SnapshotID
+	for start := 0; start < len(entries); start += removeTracksLimit {
+		end := start + removeTracksLimit
+		if end > len(entries) {
+			end = len(entries)
+		}
+		tracks := make([]spotify.TrackToRemove, 0, end-start)
+		for _, e := range entries[start:end] {
+			tracks = append(tracks, spotify.NewTrackToRemove(string(e.track.ID), []int{e.position}))
+		}
+
+		var err error
+		snapshot, err = client.RemoveTracksFromPlaylistOpt(userID, pl.ID, tracks, snapshot)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return removable, nil
+}
+
+// describePlaylistEntries lists entries numbered from 1, with the artist,
+// album and 1-based position of each track.
+func describePlaylistEntries(entries []playlistEntry) string {
+	var b strings.Builder
+	for i, e := range entries {
+		fmt.Fprintf(&b, "  %d) %s  %s  %s  position: %d\n", i+1, e.track.Name, firstOf(artistNames(e.track.Artists)), e.track.Album.Name, e.position+1)
+	}
+	return b.String()
+}
+
+// parsePositions returns the 0-based positions listed in s, such as "3" or
+// "1,4-6", counting from 1 in a playlist of total tracks.
+func parsePositions(s string, total int) (map[int]bool, error) {
+	if total == 0 {
+		return nil, errors.New("the playlist is empty")
+	}
+	indexes, err := parseChoices(s, total)
+	if err != nil {
+		return nil, fmt.Errorf("--pos: %v", err)
+	}
+	positions := make(map[int]bool, len(indexes))
+	for _, i := range indexes {
+		positions[i] = true
+	}
+	return positions, nil
+}
@@ -0,0 +1,450 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zmb3/spotify"
+)
+
+const (
+	// replaceTracksLimit is the maximum number of tracks a playlist is
+	// replaced with in one request.
+	replaceTracksLimit = 100
+	// audioFeaturesLimit is the most tracks the Web API returns the audio
+	// features of at once.
+	audioFeaturesLimit = 100
+)
+
+// sortKeys are the orders sort supports.
+var sortKeys = []string{"artist", "album", "title", "added_at", "popularity", "duration", "release_date", "tempo"}
+
+var (
+	sortPlaylistName string
+	sortBy           string
+	sortDesc         bool
+)
+
+var (
+	movePlaylistName string
+	moveFrom         int
+	moveTo           int
+	moveLength       int
+)
+
+func newSortPlaylistCmd() *cobra.Command {
+	sortCmd := &cobra.Command{
+		Use:         "sort --p [PLAYLIST_NAME] --by [KEY]",
+		Short:       "Sort the tracks of a playlist",
+		Annotations: needsScopes(playlistModifyScopes...),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return sortPlaylist(cmd, args)
+		},
+	}
+	sortCmd.Flags().StringVar(&sortPlaylistName, "p", "", "Name, ID, URI or URL of playlist to sort.")
+	sortCmd.Flags().StringVar(&sortBy, "by", "", "What to sort by ("+strings.Join(sortKeys, ", ")+").")
+	sortCmd.Flags().BoolVar(&sortDesc, "desc", false, "Sort in descending order.")
+	addPlaylistSelectorFlags(sortCmd.Flags())
+	return sortCmd
+}
+
+func newMoveTracksCmd() *cobra.Command {
+	moveCmd := &cobra.Command{
+		Use:         "move --p [PLAYLIST_NAME] --from [POSITION] --to [POSITION]",
+		Short:       "Move tracks within a playlist",
+		Annotations: needsScopes(playlistModifyScopes...),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return moveTracks(cmd, args)
+		},
+	}
+	moveCmd.Flags().StringVar(&movePlaylistName, "p", "", "Name, ID, URI or URL of playlist to move tracks in.")
+	moveCmd.Flags().IntVar(&moveFrom, "from", 0, "Position of the first track to move, from 1.")
+	moveCmd.Flags().IntVar(&moveTo, "to", 0, "Position the first track ends up at, from 1.")
+	moveCmd.Flags().IntVar(&moveLength, "len", 1, "Number of tracks to move.")
+	addPlaylistSelectorFlags(moveCmd.Flags())
+	return moveCmd
+}
+
+func sortPlaylist(cmd *cobra.Command, args []string) error {
+	if err := checkSortKey(sortBy); err != nil {
+		return err
+	}
+
+	// current user
+	user, err := client.CurrentUser()
+	if err != nil {
+		return err
+	}
+	printInfo("User: ", user.DisplayName)
+
+	// get the playlist and its tracks
+	pl, err := resolvePlaylist(sortPlaylistName)
+	if err != nil {
+		return err
+	}
+	printInfo("Playlist: ", pl.Name)
+	entries, err := readPlaylistEntries(user.ID, pl.ID)
+	if err != nil {
+		return err
+	}
+
+	// compute the order, ties keeping theirs
+	values, err := sortValues(entries, sortBy)
+	if err != nil {
+		return err
+	}
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return values[order[i]].less(values[order[j]], sortDesc)
+	})
+
+	// apply it, keeping when the tracks were added if sorted by that
+	requests, err := applyOrder(user.ID, pl, entries, order, sortBy != "added_at")
+	if err != nil {
+		return err
+	}
+	if requests == 0 {
+		printInfof("Playlist \"%s\" is already sorted by %s.\n", pl.Name, sortBy)
+		return nil
+	}
+	printInfof("Sorted playlist \"%s\" by %s in %d requests.\n", pl.Name, sortBy, requests)
+	return nil
+}
+
+func moveTracks(cmd *cobra.Command, args []string) error {
+	// current user
+	user, err := client.CurrentUser()
+	if err != nil {
+		return err
+	}
+	printInfo("User: ", user.DisplayName)
+
+	// get the playlist
+	pl, err := resolvePlaylist(movePlaylistName)
+	if err != nil {
+		return err
+	}
+	printInfo("Playlist: ", pl.Name)
+
+	// validate the range against the playlist
+	n := int(pl.Tracks.Total)
+	if moveLength < 1 {
+		return errors.New("--len must be at least 1")
+	}
+	if moveFrom < 1 || moveFrom+moveLength-1 > n {
+		return fmt.Errorf("--from %d --len %d is out of range, playlist %s has %d tracks", moveFrom, moveLength, pl.Name, n)
+	}
+	if moveTo < 1 || moveTo+moveLength-1 > n {
+		return fmt.Errorf("--to %d --len %d is out of range, playlist %s has %d tracks", moveTo, moveLength, pl.Name, n)
+	}
+	if moveTo == moveFrom {
+		printInfo("Nothing to move.")
+		return nil
+	}
+
+	// the Web API inserts before a position counted before the move
+	before := moveTo - 1
+	if moveTo > moveFrom {
+		before += moveLength
+	}
+	_, err = client.ReorderPlaylistTracks(user.ID, pl.ID, spotify.PlaylistReorderOptions{
+		RangeStart:   moveFrom - 1,
+		RangeLength:  moveLength,
+		InsertBefore: before,
+		SnapshotID:   pl.SnapshotID,
+	})
+	if err != nil {
+		return err
+	}
+	printInfof("Moved %d tracks from position %d to %d in playlist \"%s\".\n", moveLength, moveFrom, moveTo, pl.Name)
+	return nil
+}
+
+// sortValue is what an entry is sorted by: text or a number, possibly
+// unknown.
+type sortValue struct {
+	text    string
+	number  float64
+	missing bool
+}
+
+// less reports whether v sorts before w. Unknown values go last either way.
+func (v sortValue) less(w sortValue, desc bool) bool {
+	if v.missing || w.missing {
+		return !v.missing && w.missing
+	}
+	if desc {
+		v, w = w, v
+	}
+	if v.text != w.text {
+		return v.text < w.text
+	}
+	return v.number < w.number
+}
+
+// sortValues returns what each entry sorts by for key, looking up what the
+// playlist doesn't carry.
+func sortValues(entries []playlistEntry, key string) ([]sortValue, error) {
+	values := make([]sortValue, len(entries))
+	switch key {
+	case "artist":
+		for i, e := range entries {
+			values[i] = sortValue{text: strings.ToLower(firstOf(artistNames(e.track.Artists)))}
+		}
+	case "album":
+		for i, e := range entries {
+			values[i] = sortValue{text: strings.ToLower(e.track.Album.Name)}
+		}
+	case "title":
+		for i, e := range entries {
+			values[i] = sortValue{text: strings.ToLower(e.track.Name)}
+		}
+	case "added_at":
+		// RFC 3339 timestamps in UTC sort as text
+		for i, e := range entries {
+			values[i] = sortValue{text: e.addedAt, missing: e.addedAt == ""}
+		}
+	case "popularity":
+		for i, e := range entries {
+			values[i] = sortValue{number: float64(e.track.Popularity)}
+		}
+	case "duration":
+		for i, e := range entries {
+			values[i] = sortValue{number: float64(e.track.Duration)}
+		}
+	case "release_date":
+		tracks := make([]spotify.FullTrack, len(entries))
+		for i, e := range entries {
+			tracks[i] = e.track
+		}
+		dates, err := albumReleaseDates(tracks)
+		if err != nil {
+			return nil, err
+		}
+		for i, e := range entries {
+			date := dates[e.track.Album.ID]
+			values[i] = sortValue{text: date, missing: date == ""}
+		}
+	case "tempo":
+		tempos, err := trackTempos(entries)
+		if err != nil {
+			return nil, err
+		}
+		for i, e := range entries {
+			tempo, ok := tempos[e.track.ID]
+			values[i] = sortValue{number: tempo, missing: !ok}
+		}
+	default:
+		return nil, checkSortKey(key)
+	}
+	return values, nil
+}
+
+// checkSortKey returns an error unless key is one of sortKeys.
+func checkSortKey(key string) error {
+	for _, k := range sortKeys {
+		if k == key {
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot sort by %q, use one of %s", key, strings.Join(sortKeys, ", "))
+}
+
+// trackTempos returns the tempo of the tracks of entries by ID, when known.
+func trackTempos(entries []playlistEntry) (map[spotify.ID]float64, error) {
+	var ids []spotify.ID
+	tempos := make(map[spotify.ID]float64)
+	seen := make(map[spotify.ID]bool)
+	for _, e := range entries {
+		if id := e.track.ID; id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for start := 0; start < len(ids); start += audioFeaturesLimit {
+		end := start + audioFeaturesLimit
+		if end > len(ids) {
+			end = len(ids)
+		}
+		features, err := client.GetAudioFeatures(ids[start:end]...)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range features {
+			if f != nil {
+				tempos[f.ID] = float64(f.Tempo)
+			}
+		}
+	}
+	return tempos, nil
+}
+
+// applyOrder rearranges the playlist pl, read as entries, so that order,
+// the current positions in their new order, becomes the order of its
+// tracks, and returns the number of requests made. It moves as few tracks
+// as possible, each move made on the snapshot the previous one left, so
+// edits made meanwhile make it fail rather than scramble the playlist. When
+// canReplace allows it and that takes fewer requests, the tracks are written
+// back in their new order instead, since that resets when they were added.
+func applyOrder(userID string, pl spotify.SimplePlaylist, entries []playlistEntry, order []int, canReplace bool) (int, error) {
+	moves := reorderMoves(order)
+	if len(moves) == 0 {
+		return 0, nil
+	}
+
+	// local files can't be added back, so they are only ever moved
+	writes := 1
+	if rest := len(entries) - replaceTracksLimit; rest > 0 {
+		writes += (rest + addTracksLimit - 1) / addTracksLimit
+	}
+	if canReplace && len(moves) > writes && !hasLocalTracks(entries) {
+		return writes, replaceInOrder(userID, pl, entries, order)
+	}
+
+	snapshot := pl.SnapshotID
+	for i, m := range moves {
+		m.SnapshotID = snapshot
+		var err error
+		if snapshot, err = client.ReorderPlaylistTracks(userID, pl.ID, m); err != nil {
+			if i > 0 {
+				return i, fmt.Errorf("playlist %s was left partly reordered after %d of %d moves: %v", pl.Name, i, len(moves), err)
+			}
+			return 0, err
+		}
+	}
+	return len(moves), nil
+}
+
+// replaceInOrder replaces the tracks of the playlist with entries in order:
+// the first replaceTracksLimit in one request, then the rest added
+// addTracksLimit at a time. Neither takes a snapshot, so before each the
+// playlist is checked to be at the one the previous request left, starting
+// from the snapshot the entries were read at.
+func replaceInOrder(userID string, pl spotify.SimplePlaylist, entries []playlistEntry, order []int) error {
+	ids := make([]spotify.ID, len(order))
+	for i, pos := range order {
+		ids[i] = entries[pos].track.ID
+	}
+
+	snapshot := pl.SnapshotID
+	for start := 0; start < len(ids); {
+		current, err := client.GetPlaylistOpt(userID, pl.ID, "snapshot_id")
+		if err != nil {
+			return err
+		}
+		if current.SnapshotID != snapshot {
+			if start == 0 {
+				return fmt.Errorf("playlist %s changed meanwhile, try again", pl.Name)
+			}
+			return fmt.Errorf("playlist %s changed meanwhile and was left with %d of its %d tracks", pl.Name, start, len(ids))
+		}
+
+		var end int
+		if start == 0 {
+			end = minInt(replaceTracksLimit, len(ids))
+			snapshot, err = client.ReplacePlaylistTracks(userID, pl.ID, ids[:end]...)
+		} else {
+			end = minInt(start+addTracksLimit, len(ids))
+			snapshot, err = client.AddTracksToPlaylist(userID, pl.ID, ids[start:end]...)
+		}
+		if err != nil {
+			if start == 0 {
+				return err
+			}
+			return fmt.Errorf("playlist %s was left with %d of its %d tracks: %v", pl.Name, start, len(ids), err)
+		}
+		start = end
+	}
+	return nil
+}
+
+func hasLocalTracks(entries []playlistEntry) bool {
+	for _, e := range entries {
+		if e.track.ID == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// reorderMoves returns the moves turning the current order into order,
+// which lists the current positions in their new order. The tracks in a
+// longest run already in the right relative order stay put and every
+// other one is moved once, after the track that precedes it in order.
+func reorderMoves(order []int) []spotify.PlaylistReorderOptions {
+	// rank of the track at each current position
+	ranks := make([]int, len(order))
+	for rank, pos := range order {
+		ranks[pos] = rank
+	}
+	staying := longestIncreasing(ranks)
+
+	var moves []spotify.PlaylistReorderOptions
+	current := append([]int(nil), ranks...)
+	for rank := range order {
+		if staying[rank] {
+			continue
+		}
+		from := indexOf(current, rank)
+		before := 0
+		if rank > 0 {
+			before = indexOf(current, rank-1) + 1
+		}
+		if before == from {
+			continue
+		}
+		moves = append(moves, spotify.PlaylistReorderOptions{RangeStart: from, RangeLength: 1, InsertBefore: before})
+
+		// the same move on the local copy
+		current = append(current[:from], current[from+1:]...)
+		if before > from {
+			before--
+		}
+		current = append(current[:before], append([]int{rank}, current[before:]...)...)
+	}
+	return moves
+}
+
+// longestIncreasing returns the values of a longest increasing subsequence
+// of s, a permutation of 0 to len(s)-1.
+func longestIncreasing(s []int) map[int]bool {
+	// tails[k] is the index in s ending the best subsequence of length k+1
+	var tails []int
+	prev := make([]int, len(s))
+	for i, v := range s {
+		k := sort.Search(len(tails), func(k int) bool { return s[tails[k]] >= v })
+		if k > 0 {
+			prev[i] = tails[k-1]
+		} else {
+			prev[i] = -1
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+
+	values := make(map[int]bool)
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+			values[s[i]] = true
+		}
+	}
+	return values
+}
+
+func indexOf(s []int, v int) int {
+	for i, w := range s {
+		if w == v {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zmb3/spotify"
+)
+
+// permutations returns every order of 0 to n-1.
+func permutations(n int) [][]int {
+	if n == 0 {
+		return [][]int{{}}
+	}
+	var all [][]int
+	for _, p := range permutations(n - 1) {
+		for i := 0; i <= len(p); i++ {
+			q := append(append(append([]int(nil), p[:i]...), n-1), p[i:]...)
+			all = append(all, q)
+		}
+	}
+	return all
+}
+
+func TestReorderMoves(t *testing.T) {
+	for n := 0; n <= 6; n++ {
+		for _, order := range permutations(n) {
+			// replay the moves the way the Web API applies them
+			tracks := make([]int, n)
+			for i := range tracks {
+				tracks[i] = i
+			}
+			moves := reorderMoves(order)
+			for _, m := range moves {
+				moved := tracks[m.RangeStart]
+				rest := append(append([]int(nil), tracks[:m.RangeStart]...), tracks[m.RangeStart+1:]...)
+				before := m.InsertBefore
+				if before > m.RangeStart {
+					before--
+				}
+				tracks = append(append(append([]int(nil), rest[:before]...), moved), rest[before:]...)
+			}
+			if !reflect.DeepEqual(tracks, order) {
+				t.Fatalf("order %v: moves %+v give %v", order, moves, tracks)
+			}
+
+			ranks := make([]int, n)
+			for rank, pos := range order {
+				ranks[pos] = rank
+			}
+			if want := n - len(longestIncreasing(ranks)); len(moves) != want {
+				t.Errorf("order %v: %d moves, want %d", order, len(moves), want)
+			}
+		}
+	}
+}
+
+func TestSortPlaylist(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddAudioFeatures(spotify.AudioFeatures{ID: "t100000000000000000000", Tempo: 110})
+	srv.AddAudioFeatures(spotify.AudioFeatures{ID: "t300000000000000000000", Tempo: 90})
+
+	tests := []struct {
+		args []string
+		want []spotify.ID
+	}{
+		{[]string{"--by", "title"}, []spotify.ID{"t200000000000000000000", "t100000000000000000000", "t300000000000000000000"}},
+		{[]string{"--by", "duration"}, []spotify.ID{"t200000000000000000000", "t100000000000000000000", "t300000000000000000000"}},
+		{[]string{"--by", "popularity", "--desc"}, []spotify.ID{"t100000000000000000000", "t200000000000000000000", "t300000000000000000000"}},
+		{[]string{"--by", "release_date", "--desc"}, []spotify.ID{"t300000000000000000000", "t100000000000000000000", "t200000000000000000000"}},
+		{[]string{"--by", "added_at", "--desc"}, []spotify.ID{"t200000000000000000000", "t100000000000000000000", "t300000000000000000000"}},
+		// unknown tempos go last
+		{[]string{"--by", "tempo"}, []spotify.ID{"t300000000000000000000", "t100000000000000000000", "t200000000000000000000"}},
+	}
+	for _, test := range tests {
+		id := srv.AddPlaylist(spotify.SimplePlaylist{Name: "Sort"}, "t300000000000000000000", "t100000000000000000000", "t200000000000000000000")
+		args := append([]string{"sort", "--p", string(id)}, test.args...)
+		if _, err := execute(t, srv, args...); err != nil {
+			t.Fatalf("%v: %v", test.args, err)
+		}
+		if got := srv.PlaylistTracks(id); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got tracks %v, want %v", test.args, got, test.want)
+		}
+	}
+
+	// sorting by when tracks were added moves them, keeping that
+	id := srv.AddPlaylist(spotify.SimplePlaylist{Name: "Added"}, "t300000000000000000000", "t100000000000000000000", "t200000000000000000000")
+	for _, args := range [][]string{{"--desc"}, nil} {
+		if _, err := execute(t, srv, append([]string{"sort", "--p", string(id), "--by", "added_at"}, args...)...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := srv.PlaylistTracks(id), []spotify.ID{"t300000000000000000000", "t100000000000000000000", "t200000000000000000000"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got tracks %v sorted back by added_at, want %v", got, want)
+	}
+
+	// the key is checked before the playlist is looked up
+	if _, err := execute(t, srv, "sort", "--p", "Missing", "--by", "color"); err == nil || !strings.Contains(err.Error(), "cannot sort by") {
+		t.Errorf("got %v, want an error for an unknown key", err)
+	}
+}
+
+func TestSortLargePlaylist(t *testing.T) {
+	srv := newTestServer(t)
+
+	// reversed, so sorting moves all but one track
+	var ids, want []spotify.ID
+	for i := 0; i < 150; i++ {
+		id := spotify.ID(fmt.Sprintf("s%021d", i))
+		srv.AddTrack(testTrack(string(id), string(id), "Artist", "Album", 200000, 10))
+		ids = append([]spotify.ID{id}, ids...)
+		want = append(want, id)
+	}
+	id := srv.AddPlaylist(spotify.SimplePlaylist{Name: "Large"}, ids...)
+
+	out, err := execute(t, srv, "sort", "--p", "Large", "--by", "title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.PlaylistTracks(id); !reflect.DeepEqual(got, want) {
+		t.Errorf("got tracks %v, want %v", got, want)
+	}
+	// replaced with the first 100 tracks, then the rest added
+	if !strings.Contains(out, "in 2 requests") {
+		t.Errorf("expected the tracks to be written back in 2 requests:\n%s", out)
+	}
+
+	// sorting by when tracks were added still moves them
+	out, err = execute(t, srv, "sort", "--p", "Large", "--by", "added_at", "--desc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "in 149 requests") {
+		t.Errorf("expected the tracks to be moved:\n%s", out)
+	}
+}
+
+func TestMoveTracks(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddTrack(testTrack("t400000000000000000000", "Crawling", "Linkin Park", "Hybrid Theory", 209000, 60))
+	srv.AddTrack(testTrack("t500000000000000000000", "Papercut", "Linkin Park", "Hybrid Theory", 184000, 50))
+
+	tests := []struct {
+		args    []string
+		want    []spotify.ID
+		wantErr bool
+	}{
+		{[]string{"--from", "4", "--to", "1", "--len", "2"}, []spotify.ID{"t400000000000000000000", "t500000000000000000000", "t100000000000000000000", "t200000000000000000000", "t300000000000000000000"}, false},
+		{[]string{"--from", "1", "--to", "3"}, []spotify.ID{"t200000000000000000000", "t300000000000000000000", "t100000000000000000000", "t400000000000000000000", "t500000000000000000000"}, false},
+		{[]string{"--from", "1", "--to", "4", "--len", "2"}, []spotify.ID{"t300000000000000000000", "t400000000000000000000", "t500000000000000000000", "t100000000000000000000", "t200000000000000000000"}, false},
+		{[]string{"--from", "5", "--to", "1", "--len", "2"}, nil, true},
+		{[]string{"--from", "1", "--to", "0"}, nil, true},
+	}
+	for _, test := range tests {
+		id := srv.AddPlaylist(spotify.SimplePlaylist{Name: "Move"}, "t100000000000000000000", "t200000000000000000000", "t300000000000000000000", "t400000000000000000000", "t500000000000000000000")
+		_, err := execute(t, srv, append([]string{"move", "--p", string(id)}, test.args...)...)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%v: expected an error", test.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %v", test.args, err)
+		}
+		if got := srv.PlaylistTracks(id); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got tracks %v, want %v", test.args, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/masroorhasan/spotifycli/internal/replay"
+)
+
+var (
+	record = flag.Bool("record", false, "re-record the fixtures in testdata/fake against the fake Web API")
+	update = flag.Bool("update", false, "update golden files")
+)
+
+// goldenTests run commands against exchanges with the fake Web API recorded
+// in testdata/fake/NAME.json and compare their output with NAME.golden. The
+// fixtures are only ever written by -record, never by hand.
+var goldenTests = []struct {
+	name string
+	args []string
+}{
+	{"search_tracks", []string{"search", "--t", "tr", "--q", "numb"}},
+	{"search_albums", []string{"search", "--t", "al", "--q", "meteora"}},
+	{"search_artists", []string{"search", "--t", "ar", "--q", "linkin"}},
+	{"search_playlists", []string{"search", "--t", "pl", "--q", "mix"}},
+	{"list", []string{"list", "--p", "Mix"}},
+	{"playlists", []string{"playlists"}},
+	{"show", []string{"show", "--tid", "t200000000000000000000"}},
+}
+
+func TestGolden(t *testing.T) {
+	for _, test := range goldenTests {
+		t.Run(test.name, func(t *testing.T) {
+			fixture := filepath.Join("testdata", "fake", test.name+".json")
+			golden := filepath.Join("testdata", "fake", test.name+".golden")
+
+			if *record {
+				srv := newTestServer(t)
+				target, _ := url.Parse(srv.URL())
+				rec := replay.NewRecorder(&rewriteTransport{target: target, base: srv.Client().Transport})
+				if _, err := executeWith(t, &http.Client{Transport: rec}, test.args...); err != nil {
+					t.Fatal(err)
+				}
+				if err := rec.Save(fixture); err != nil {
+					t.Fatal(err)
+				}
+			}
+			runReplay(t, fixture, golden, test.args)
+		})
+	}
+}
+
+// TestCaptures replays exchanges captured from Spotify with
+// SPOTIFYCLI_RECORD into testdata/replay/NAME.json. NAME.args holds the
+// command line of the capture, one argument per line, and NAME.golden its
+// output.
+func TestCaptures(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "replay", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Skip("no captures from Spotify in testdata/replay, record one with SPOTIFYCLI_RECORD as the README describes")
+	}
+	for _, fixture := range fixtures {
+		name := strings.TrimSuffix(fixture, ".json")
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			b, err := ioutil.ReadFile(name + ".args")
+			if err != nil {
+				t.Fatal(err)
+			}
+			args := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+			runReplay(t, fixture, name+".golden", args)
+		})
+	}
+}
+
+// runReplay runs the command args against the exchanges recorded in fixture
+// and compares its output with golden, rewriting golden with -update or
+// -record.
+func runReplay(t *testing.T, fixture, golden string, args []string) {
+	replayer, err := replay.Load(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := executeWith(t, &http.Client{Transport: replayer}, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unused := replayer.Unused(); len(unused) > 0 {
+		t.Errorf("recorded requests never made: %v", unused)
+	}
+
+	if *update || *record {
+		if err := ioutil.WriteFile(golden, []byte(out), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != string(want) {
+		t.Errorf("output differs from %s:\ngot:\n%s\nwant:\n%s", golden, out, want)
+	}
+}
@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/zmb3/spotify"
+)
+
+// name matching modes of --match
+const (
+	matchExact      = "exact"
+	matchIgnoreCase = "ignore-case"
+	matchPrefix     = "prefix"
+)
+
+// idPattern matches Spotify IDs, which are 22 characters of base 62.
+var idPattern = regexp.MustCompile(`^[0-9A-Za-z]{22}$`)
+
+var (
+	// playlist selector flags
+	playlistOwner string
+	playlistIndex int
+	playlistMatch string
+)
+
+// addPlaylistSelectorFlags adds the flags narrowing down which playlist --p
+// refers to when several match.
+func addPlaylistSelectorFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&playlistOwner, "owner", "", "Only consider playlists of this owner, by ID or display name.")
+	flags.IntVar(&playlistIndex, "index", 0, "Pick the Nth of the playlists matching --p, as listed when ambiguous.")
+	flags.StringVar(&playlistMatch, "match", matchExact, "How --p matches playlist names (exact, ignore-case, prefix).")
+}
+
+// parseSpotifyID returns the ID in ref when ref is a spotify: URI or an
+// open.spotify.com URL of an item of kind, such as "playlist". It reports
+// false for anything else, which may be a raw ID or a name.
+func parseSpotifyID(ref, kind string) (spotify.ID, bool, error) {
+	var parts []string
+	switch {
+	case strings.HasPrefix(ref, "spotify:"):
+		// spotify:playlist:ID, or spotify:user:USER:playlist:ID
+		parts = strings.Split(ref, ":")
+	case strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "http://"):
+		// https://open.spotify.com/playlist/ID?si=..., possibly under
+		// /user/USER or a localized /intl-xx prefix
+		u, err := url.Parse(ref)
+		if err != nil {
+			return "", false, err
+		}
+		if u.Host != "open.spotify.com" && u.Host != "play.spotify.com" {
+			return "", false, fmt.Errorf("not a Spotify URL: %s", ref)
+		}
+		parts = strings.Split(strings.Trim(u.Path, "/"), "/")
+	default:
+		return "", false, nil
+	}
+
+	for i := len(parts) - 2; i >= 0; i-- {
+		if parts[i] == kind && parts[i+1] != "" {
+			return spotify.ID(parts[i+1]), true, nil
+		}
+	}
+	return "", false, fmt.Errorf("not a %s URI or URL: %s", kind, ref)
+}
+
+// resolveTrackID returns the ID of the track ref refers to, by ID, URI or
+// URL.
+func resolveTrackID(ref string) (spotify.ID, error) {
+	if ref == "" {
+		return "", fmt.Errorf("no track given")
+	}
+	id, ok, err := parseSpotifyID(ref, "track")
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		id = spotify.ID(ref)
+	}
+	if !idPattern.MatchString(string(id)) {
+		return "", fmt.Errorf("invalid track ID: %s", ref)
+	}
+	return id, nil
+}
+
+// resolvePlaylist returns the playlist ref refers to. A URI or URL is
+// fetched directly, like an ID, which falls back to a name when no playlist
+// has it. Names are looked up among the current user's playlists; when
+// several share the name, the selector flags or the user pick one.
+func resolvePlaylist(ref string) (spotify.SimplePlaylist, error) {
+	id, ok, err := parseSpotifyID(ref, "playlist")
+	if err != nil {
+		return spotify.SimplePlaylist{}, err
+	}
+	if ok || idPattern.MatchString(ref) {
+		if !ok {
+			id = spotify.ID(ref)
+		}
+		p, err := getPlaylist(id)
+		switch {
+		case err == nil:
+			return p, nil
+		case !isNotFound(err):
+			return spotify.SimplePlaylist{}, err
+		case ok:
+			return spotify.SimplePlaylist{}, fmt.Errorf("playlist not found: %s", ref)
+		}
+	}
+	match, err := playlistNameMatcher(playlistMatch)
+	if err != nil {
+		return spotify.SimplePlaylist{}, err
+	}
+
+	// match across every page of the current user's playlists
+	var candidates []spotify.SimplePlaylist
+	it := newPlaylistIterator()
+	for it.Next() {
+		p := it.Playlist()
+		if match(p.Name, ref) && ownedBy(p, playlistOwner) {
+			candidates = append(candidates, p)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return spotify.SimplePlaylist{}, err
+	}
+	return selectPlaylist(ref, candidates)
+}
+
+// playlistFields are the fields of a playlist fetched by ID.
+const playlistFields = "collaborative,id,name,owner,public,snapshot_id,tracks.total,uri"
+
+// getPlaylist fetches the playlist with id, which may be outside the current
+// user's library.
+func getPlaylist(id spotify.ID) (spotify.SimplePlaylist, error) {
+	full, err := client.GetPlaylistOpt("", id, playlistFields)
+	if err != nil {
+		return spotify.SimplePlaylist{}, err
+	}
+	p := full.SimplePlaylist
+	p.Tracks.Total = uint(full.Tracks.Total)
+	return p, nil
+}
+
+// isNotFound reports whether err is the Web API answering that the playlist
+// asked for doesn't exist. An ID it can't parse is refused with a 400 of its
+// own rather than a 404.
+func isNotFound(err error) bool {
+	e, ok := err.(spotify.Error)
+	if !ok {
+		return false
+	}
+	return e.Status == http.StatusNotFound ||
+		e.Status == http.StatusBadRequest && strings.EqualFold(e.Message, "Invalid playlist Id")
+}
+
+// selectPlaylist picks the playlist ref refers to among candidates.
+func selectPlaylist(ref string, candidates []spotify.SimplePlaylist) (spotify.SimplePlaylist, error) {
+	if len(candidates) == 0 {
+		return spotify.SimplePlaylist{}, fmt.Errorf("playlist not found: %s", ref)
+	}
+
+	// a loose match doesn't hide the playlist named exactly so
+	if len(candidates) > 1 && playlistMatch != matchExact {
+		var exact []spotify.SimplePlaylist
+		for _, p := range candidates {
+			if p.Name == ref {
+				exact = append(exact, p)
+			}
+		}
+		if len(exact) == 1 {
+			return exact[0], nil
+		}
+	}
+
+	switch {
+	case playlistIndex > 0:
+		if playlistIndex > len(candidates) {
+			return spotify.SimplePlaylist{}, fmt.Errorf("--index %d is out of range, %d playlists match %q", playlistIndex, len(candidates), ref)
+		}
+		return candidates[playlistIndex-1], nil
+	case len(candidates) == 1:
+		return candidates[0], nil
+	case isInteractive():
+		fmt.Fprintf(stderr, "%d playlists match %q:\n%s", len(candidates), ref, describePlaylists(candidates))
+		i, err := pickOne("Playlist", len(candidates))
+		if err != nil {
+			return spotify.SimplePlaylist{}, err
+		}
+		return candidates[i], nil
+	default:
+		return spotify.SimplePlaylist{}, fmt.Errorf("playlist %q is ambiguous, %d playlists match:\n%spick one with --index N or --owner OWNER, or give its ID",
+			ref, len(candidates), describePlaylists(candidates))
+	}
+}
+
+// describePlaylists lists playlists numbered from 1, with their ID, owner
+// and track count.
+func describePlaylists(playlists []spotify.SimplePlaylist) string {
+	var b strings.Builder
+	for i, p := range playlists {
+		fmt.Fprintf(&b, "  %d) %s  %s  owner: %s  tracks: %d\n", i+1, p.ID, p.Name, playlistOwnerName(p), p.Tracks.Total)
+	}
+	return b.String()
+}
+
+// playlistNameMatcher returns the function matching playlist names against
+// a reference in mode.
+func playlistNameMatcher(mode string) (func(name, ref string) bool, error) {
+	switch mode {
+	case matchExact:
+		return func(name, ref string) bool { return name == ref }, nil
+	case matchIgnoreCase:
+		return strings.EqualFold, nil
+	case matchPrefix:
+		return func(name, ref string) bool {
+			return strings.HasPrefix(strings.ToLower(name), strings.ToLower(ref))
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown --match %s, use %s, %s or %s", mode, matchExact, matchIgnoreCase, matchPrefix)
+	}
+}
+
+// ownedBy reports whether owner, an ID or display name, owns p. An empty
+// owner matches any playlist.
+func ownedBy(p spotify.SimplePlaylist, owner string) bool {
+	return owner == "" || strings.EqualFold(p.Owner.ID, owner) || strings.EqualFold(p.Owner.DisplayName, owner)
+}
+
+func playlistOwnerName(p spotify.SimplePlaylist) string {
+	if p.Owner.DisplayName != "" {
+		return p.Owner.DisplayName
+	}
+	return p.Owner.ID
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zmb3/spotify"
+)
+
+func TestParseSpotifyID(t *testing.T) {
+	tests := []struct {
+		ref     string
+		kind    string
+		want    spotify.ID
+		wantOK  bool
+		wantErr bool
+	}{
+		{"37i9dQZF1DXcBWIGoYBM5M", "playlist", "", false, false},
+		{"My Mix", "playlist", "", false, false},
+		{"spotify:playlist:37i9dQZF1DXcBWIGoYBM5M", "playlist", "37i9dQZF1DXcBWIGoYBM5M", true, false},
+		{"spotify:user:alice:playlist:37i9dQZF1DXcBWIGoYBM5M", "playlist", "37i9dQZF1DXcBWIGoYBM5M", true, false},
+		{"https://open.spotify.com/playlist/37i9dQZF1DXcBWIGoYBM5M?si=abc", "playlist", "37i9dQZF1DXcBWIGoYBM5M", true, false},
+		{"https://open.spotify.com/intl-de/playlist/37i9dQZF1DXcBWIGoYBM5M", "playlist", "37i9dQZF1DXcBWIGoYBM5M", true, false},
+		{"https://open.spotify.com/user/alice/playlist/37i9dQZF1DXcBWIGoYBM5M", "playlist", "37i9dQZF1DXcBWIGoYBM5M", true, false},
+		{"spotify:track:6rqhFgbbKwnb9MLmUQDhG6", "track", "6rqhFgbbKwnb9MLmUQDhG6", true, false},
+		{"https://open.spotify.com/track/6rqhFgbbKwnb9MLmUQDhG6", "track", "6rqhFgbbKwnb9MLmUQDhG6", true, false},
+		{"spotify:track:6rqhFgbbKwnb9MLmUQDhG6", "playlist", "", false, true},
+		{"https://example.com/playlist/37i9dQZF1DXcBWIGoYBM5M", "playlist", "", false, true},
+	}
+	for _, test := range tests {
+		id, ok, err := parseSpotifyID(test.ref, test.kind)
+		if test.wantErr != (err != nil) {
+			t.Errorf("%s: got error %v, want error: %v", test.ref, err, test.wantErr)
+			continue
+		}
+		if id != test.want || ok != test.wantOK {
+			t.Errorf("%s: got %q, %v, want %q, %v", test.ref, id, ok, test.want, test.wantOK)
+		}
+	}
+}
+
+func TestPlaylistReferences(t *testing.T) {
+	srv := newTestServer(t)
+	id := srv.Playlists()[0].ID
+
+	for _, ref := range []string{"Mix", string(id), "spotify:playlist:" + string(id), "https://open.spotify.com/playlist/" + string(id) + "?si=abc"} {
+		out, err := execute(t, srv, "list", "--p", ref)
+		if err != nil {
+			t.Fatalf("%s: %v", ref, err)
+		}
+		if !strings.Contains(out, "Faint") {
+			t.Errorf("%s: expected tracks of Mix in output:\n%s", ref, out)
+		}
+	}
+
+	// playlists outside the library are fetched by ID
+	srv.AddUser(spotify.PrivateUser{User: spotify.User{ID: "bob", DisplayName: "Bob"}})
+	other := srv.AddOtherPlaylist(spotify.SimplePlaylist{Name: "Bob's", Owner: spotify.User{ID: "bob", DisplayName: "Bob"}}, "t300000000000000000000")
+	out, err := execute(t, srv, "list", "--p", "https://open.spotify.com/playlist/"+string(other))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Numb - Live") {
+		t.Errorf("expected tracks of Bob's playlist in output:\n%s", out)
+	}
+	for _, ref := range []string{"spotify:playlist:missing", "spotify:playlist:pl99999999999999999999"} {
+		if _, err := execute(t, srv, "list", "--p", ref); err == nil || !strings.Contains(err.Error(), "playlist not found") {
+			t.Errorf("%s: got %v, want an error for a missing playlist", ref, err)
+		}
+	}
+
+	// tracks are given by URI or URL as well
+	if _, err := execute(t, srv, "aid", "--tid", "spotify:track:t300000000000000000000", "--p", "spotify:playlist:"+string(id)); err != nil {
+		t.Fatal(err)
+	}
+	if tracks := srv.PlaylistTracks(id); len(tracks) != 3 || tracks[2] != "t300000000000000000000" {
+		t.Errorf("got tracks %v after adding t3", tracks)
+	}
+	out, err = execute(t, srv, "show", "--tid", "https://open.spotify.com/track/t200000000000000000000?si=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Faint") {
+		t.Errorf("expected track t2 in output:\n%s", out)
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{spotify.Error{Status: 404, Message: "Not found."}, true},
+		{spotify.Error{Status: 400, Message: "Invalid playlist Id"}, true},
+		{spotify.Error{Status: 400, Message: "Invalid limit"}, false},
+		{spotify.Error{Status: 403, Message: "Forbidden"}, false},
+		{spotify.Error{Status: 500, Message: "Server error"}, false},
+		{errors.New("connection refused"), false},
+	}
+	for _, test := range tests {
+		if got := isNotFound(test.err); got != test.want {
+			t.Errorf("isNotFound(%v) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
+
+func TestAmbiguousPlaylist(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddUser(spotify.PrivateUser{User: spotify.User{ID: "bob", DisplayName: "Bob"}})
+	bobs := srv.AddPlaylist(spotify.SimplePlaylist{Name: "Mix", Owner: spotify.User{ID: "bob", DisplayName: "Bob"}}, "t300000000000000000000")
+	srv.AddPlaylist(spotify.SimplePlaylist{Name: "Mixtape"})
+	isInteractive = func() bool { return false }
+	defer func() { isInteractive = origIsInteractive }()
+
+	_, err := execute(t, srv, "list", "--p", "Mix")
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") || !strings.Contains(err.Error(), string(bobs)) || !strings.Contains(err.Error(), "owner: Bob") {
+		t.Fatalf("expected an error listing both playlists, got %v", err)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--owner", "bob"}, "Road to Revolution"},
+		{[]string{"--owner", "Alice"}, "Faint"},
+		{[]string{"--index", "2"}, "Road to Revolution"},
+		{[]string{"--p", "mixt", "--match", "prefix"}, ""},
+		{[]string{"--p", "MIXTAPE", "--match", "ignore-case"}, ""},
+	}
+	for _, test := range tests {
+		args := append([]string{"list", "--p", "Mix"}, test.args...)
+		out, err := execute(t, srv, args...)
+		if err != nil {
+			t.Errorf("%v: %v", test.args, err)
+			continue
+		}
+		if !strings.Contains(out, test.want) {
+			t.Errorf("%v: expected %q in output:\n%s", test.args, test.want, out)
+		}
+	}
+
+	if _, err := execute(t, srv, "list", "--p", "Mix", "--index", "3"); err == nil {
+		t.Error("expected an error for an index out of range")
+	}
+	if _, err := execute(t, srv, "list", "--p", "mix", "--match", "fuzzy"); err == nil {
+		t.Error("expected an error for an unknown match mode")
+	}
+}
+
+func TestPickAmbiguousPlaylist(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddPlaylist(spotify.SimplePlaylist{Name: "Mix"}, "t300000000000000000000")
+
+	isInteractive = func() bool { return true }
+	stdin = strings.NewReader("2\n")
+	defer func() {
+		isInteractive = origIsInteractive
+		stdin = origStdin
+	}()
+	out, err := execute(t, srv, "list", "--p", "Mix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Road to Revolution") {
+		t.Errorf("expected the picked playlist in output:\n%s", out)
+	}
+}
@@ -1,26 +1,49 @@
 package cmd
 
 import (
-	"encoding/json"
-	"io/ioutil"
+	"context"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
-	"os/user"
-	"path/filepath"
 
+	"github.com/masroorhasan/spotifycli/internal/replay"
 	"github.com/spf13/cobra"
 	"github.com/zmb3/spotify"
 	"golang.org/x/oauth2"
 )
 
 const (
-	tokenFile   = ".sptok"
+	tokenFile   = "token.json"
 	redirectURI = "http://localhost:8080/callback"
+	apiURL      = "https://api.spotify.com/v1/"
+)
+
+const (
+	// legacyTokenFile is where earlier versions kept the token, in the home
+	// directory.
+	legacyTokenFile = ".sptok"
+)
+
+const (
+	// recordEnv names a file to record the Web API exchanges of a run into,
+	// scrubbed, for use as a test fixture.
+	recordEnv = "SPOTIFYCLI_RECORD"
 )
 
 var (
-	auth   spotify.Authenticator
-	client spotify.Client
+	oauthConfig *oauth2.Config
+	httpClient  *http.Client
+	tokens      *tokenStore
+	tokenSource oauth2.TokenSource
+	client      spotifyClient
+	recorder    *replay.Recorder
+
+	// profileName is the --profile flag, activeProfile the profile in use.
+	profileName   string
+	activeProfile string
+	// market is the country code of the selected profile.
+	market string
 )
 
 // NewRootCmd gets the root cmd.
@@ -31,9 +54,16 @@ func NewRootCmd() *cobra.Command {
 		PersistentPreRun:  prerun,
 		PersistentPostRun: postrun,
 	}
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputTable, "Output format (table, json, csv, tsv, yaml).")
+	rootCmd.PersistentFlags().StringVar(&outputTemplate, "format", "", "Format each result with a Go template, e.g. '{{.Name}} - {{.Artist}}'.")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Profile to use (default $"+profileEnv+" or the one selected with 'profile use').")
+
 	// auth ops
 	rootCmd.AddCommand(newLoginCmd())
 	rootCmd.AddCommand(newLogoutCmd())
+	rootCmd.AddCommand(newAuthCmd())
+	rootCmd.AddCommand(newWhoamiCmd())
+	rootCmd.AddCommand(newProfileCmd())
 
 	// search ops
 	rootCmd.AddCommand(newSearchCmd())
@@ -47,132 +77,140 @@ func NewRootCmd() *cobra.Command {
 	rootCmd.AddCommand(newAddTrackByIDToPlaylistCmd())
 	rootCmd.AddCommand(newAddTrackByNameToPlaylistCmd())
 	rootCmd.AddCommand(newRemoveTrackFromPlaylistCmd())
+	rootCmd.AddCommand(newDedupePlaylistCmd())
+	rootCmd.AddCommand(newSortPlaylistCmd())
+	rootCmd.AddCommand(newMoveTracksCmd())
+	rootCmd.AddCommand(newShufflePlaylistCmd())
 	rootCmd.AddCommand(newListPlaylistTracksCmd())
 	rootCmd.AddCommand(newShowTrackCmd())
 	return rootCmd
 }
 
 func prerun(cmd *cobra.Command, args []string) {
-	// initialize authenticator
-	auth = spotify.NewAuthenticator(
-		redirectURI,
-		spotify.ScopeUserReadPrivate,
-		spotify.ScopeUserReadCurrentlyPlaying,
-		spotify.ScopePlaylistReadCollaborative,
-		spotify.ScopePlaylistModifyPrivate,
-		spotify.ScopePlaylistModifyPublic)
-	auth.SetAuthInfo(os.Getenv("SPOTIFY_ID"), os.Getenv("SPOTIFY_SECRET"))
-
-	// exit early
-	if cmd.Use == "login" || cmd.Use == "logout" {
-		return
+	// validate flags before going through authentication
+	if err := validateOutput(); err != nil {
+		log.Fatal(err)
 	}
 
-	// get token
-	token, err := getToken()
+	// read the config file, environment variables and flags take precedence
+	cfg, err := loadConfig()
 	if err != nil {
-		if err := authorize(cmd, args); err != nil {
-			log.Fatal(err)
+		log.Fatal(err)
+	}
+	if cfg.Output != "" && !cmd.Flags().Changed("output") {
+		outputFormat = cfg.Output
+		if err := validateOutput(); err != nil {
+			log.Fatalf("%s: %v", configFile, err)
 		}
 	}
-	client = auth.NewClient(token)
-}
 
-func postrun(cmd *cobra.Command, args []string) {
-	// exit early
-	if cmd.Use == "login" || cmd.Use == "logout" {
+	// profile commands only edit the config file
+	if cmd.HasParent() && cmd.Parent().Name() == "profile" {
 		return
 	}
 
-	// refresh token
-	currTok, err := client.Token()
+	// select the profile
+	name := cfg.selectedProfile()
+	p, err := cfg.lookupProfile(name)
 	if err != nil {
 		log.Fatal(err)
 	}
-	token, err := getToken()
+	activeProfile, market = name, p.Market
+
+	// initialize oauth2 config and the http client under it
+	redirect := redirectURI
+	if cfg.RedirectURI != "" {
+		redirect = cfg.RedirectURI
+	}
+	oauthConfig = &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		RedirectURL:  redirect,
+		// logging in grants what any command needs, so it only happens once
+		Scopes: allScopes(cmd.Root()),
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  spotify.AuthURL,
+			TokenURL: spotify.TokenURL,
+		},
+	}
+	httpClient = newHTTPClient()
+	store, err := newTokenStore(name)
 	if err != nil {
 		log.Fatal(err)
 	}
-	if token != currTok {
-		if err := persistToken(token); err != nil {
-			log.Fatal(err)
-		}
+	tokens = store
+
+	// exit early
+	if cmd.Use == "login" || cmd.Use == "logout" {
+		return
 	}
-}
 
-func persistToken(token *oauth2.Token) error {
-	u, err := user.Current()
+	// catalog commands can do without a user
+	withoutUser, err := useAppOnly(cmd, oauthConfig, tokens)
 	if err != nil {
-		return err
+		log.Fatal(err)
+	}
+	if withoutUser {
+		appOnly = true
+		client = newWebAPIClient(newAppOnlyClient(oauthConfig))
+		return
 	}
 
-	// detect if file exists
-	tokPath := filepath.Join(u.HomeDir, tokenFile)
-	_, err = os.Stat(tokPath)
-
-	// create and write
-	if os.IsNotExist(err) {
-		file, err := os.Create(tokPath)
-		if err != nil {
-			return err
+	// get token
+	token, err := tokens.Load()
+	if err != nil {
+		// reported by the status commands instead
+		if cmd.Name() == "status" || cmd.Name() == "whoami" {
+			return
 		}
-		defer file.Close()
-
-		b, err := json.Marshal(token)
-		if err != nil {
-			return err
+		if err := authorize(cmd, args); err != nil {
+			log.Fatal(err)
 		}
-
-		// write
-		_, err = file.Write(b)
-		if err != nil {
-			return err
+		if token, err = tokens.Load(); err != nil {
+			log.Fatal(err)
 		}
-		return file.Sync()
-	}
-	return nil
-}
-
-func getToken() (*oauth2.Token, error) {
-	u, err := user.Current()
-	if err != nil {
-		return nil, err
 	}
 
-	// detect if file exists
-	tokPath := filepath.Join(u.HomeDir, tokenFile)
-	_, err = os.Stat(tokPath)
-
-	// exit early
-	if os.IsNotExist(err) {
-		return nil, err
+	// ask again for consent when the command needs more than was granted
+	granted := tokenScopes(token)
+	if missing := missingScopes(granted, commandScopes(cmd)); len(missing) > 0 {
+		fmt.Fprintln(stderr, missingScopesMessage(cmd, missing))
+		oauthConfig.Scopes = unionScopes(granted, commandScopes(cmd))
+		if err := authorize(cmd, args); err != nil {
+			log.Fatal(err)
+		}
+		if token, err = tokens.Load(); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	// read data
-	data, err := ioutil.ReadFile(tokPath)
-	if err != nil {
-		return nil, err
-	}
+	// refreshed tokens are saved as soon as oauth2 obtains them
+	tokenSource = newSavingTokenSource(oauthConfig, token, tokens)
+	client = newWebAPIClient(oauth2.NewClient(oauthContext(), tokenSource))
+}
 
-	// unmarshal data
-	var token oauth2.Token
-	if err := json.Unmarshal(data, &token); err != nil {
-		return nil, err
+func postrun(cmd *cobra.Command, args []string) {
+	// save recorded exchanges
+	if recorder != nil {
+		if err := recorder.Save(os.Getenv(recordEnv)); err != nil {
+			log.Fatal(err)
+		}
 	}
-	return &token, nil
 }
 
-func deleteToken() error {
-	u, err := user.Current()
-	if err != nil {
-		return err
+// newHTTPClient returns the client oauth2 uses for token requests and as the
+// transport under the API client. When recordEnv is set, exchanges go
+// through a recorder.
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport
+	if os.Getenv(recordEnv) != "" {
+		recorder = replay.NewRecorder(transport)
+		transport = recorder
 	}
+	return &http.Client{Transport: transport}
+}
 
-	// detect if file exists
-	tokPath := filepath.Join(u.HomeDir, tokenFile)
-	_, err = os.Stat(tokPath)
-	if os.IsNotExist(err) {
-		return err
-	}
-	return os.Remove(tokPath)
+// oauthContext returns a context carrying httpClient for oauth2.
+func oauthContext() context.Context {
+	return context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
 }
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zmb3/spotify"
+	"golang.org/x/oauth2"
+)
+
+// scopesAnnotation is the command annotation listing the scopes the command
+// needs, separated by spaces.
+const scopesAnnotation = "spotifycli/scopes"
+
+var (
+	// playlistReadScopes are needed to read the playlists of the user.
+	playlistReadScopes = []string{
+		spotify.ScopeUserReadPrivate,
+		spotify.ScopePlaylistReadCollaborative,
+	}
+	// playlistModifyScopes are needed to change them.
+	playlistModifyScopes = append([]string{
+		spotify.ScopePlaylistModifyPrivate,
+		spotify.ScopePlaylistModifyPublic,
+	}, playlistReadScopes...)
+
+	// legacyScopes were requested for every token before commands declared
+	// their scopes. Tokens stored back then don't record theirs.
+	legacyScopes = []string{
+		spotify.ScopeUserReadPrivate,
+		spotify.ScopeUserReadCurrentlyPlaying,
+		spotify.ScopePlaylistReadCollaborative,
+		spotify.ScopePlaylistModifyPrivate,
+		spotify.ScopePlaylistModifyPublic,
+	}
+)
+
+// needsScopes returns the annotations declaring that a command needs
+// scopes.
+func needsScopes(scopes ...string) map[string]string {
+	return map[string]string{scopesAnnotation: strings.Join(scopes, " ")}
+}
+
+// commandScopes returns the scopes cmd declared it needs.
+func commandScopes(cmd *cobra.Command) []string {
+	return strings.Fields(cmd.Annotations[scopesAnnotation])
+}
+
+// allScopes returns the scopes needed by cmd and its subcommands, sorted.
+func allScopes(cmd *cobra.Command) []string {
+	scopes := commandScopes(cmd)
+	for _, c := range cmd.Commands() {
+		scopes = append(scopes, allScopes(c)...)
+	}
+	return unionScopes(scopes)
+}
+
+// tokenScopes returns the scopes granted to token, assuming legacyScopes
+// when it doesn't record them.
+func tokenScopes(token *oauth2.Token) []string {
+	if scopes := grantedScopes(token); len(scopes) > 0 {
+		return scopes
+	}
+	return legacyScopes
+}
+
+// missingScopes returns the scopes of needed that are not in granted.
+func missingScopes(granted, needed []string) []string {
+	has := make(map[string]bool, len(granted))
+	for _, scope := range granted {
+		has[scope] = true
+	}
+	var missing []string
+	for _, scope := range needed {
+		if !has[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// unionScopes returns the scopes in any of lists, sorted and without
+// duplicates.
+func unionScopes(lists ...[]string) []string {
+	seen := make(map[string]bool)
+	var union []string
+	for _, scopes := range lists {
+		for _, scope := range scopes {
+			if !seen[scope] {
+				seen[scope] = true
+				union = append(union, scope)
+			}
+		}
+	}
+	sort.Strings(union)
+	return union
+}
+
+// missingScopesMessage explains that cmd needs scopes the token lacks.
+func missingScopesMessage(cmd *cobra.Command, missing []string) string {
+	noun := "scope"
+	if len(missing) > 1 {
+		noun = "scopes"
+	}
+	return fmt.Sprintf("The %s command needs the %s %s, which the stored token was not granted. Log in again to grant access.",
+		cmd.Name(), noun, strings.Join(missing, ", "))
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/zmb3/spotify"
+	"golang.org/x/oauth2"
+)
+
+func findCommand(t *testing.T, root *cobra.Command, args ...string) *cobra.Command {
+	cmd, _, err := root.Find(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+func TestCommandScopes(t *testing.T) {
+	root := NewRootCmd()
+
+	// logging in asks for everything commands need
+	if got := allScopes(root); !reflect.DeepEqual(got, unionScopes(legacyScopes)) {
+		t.Errorf("got all scopes %v, want %v", got, unionScopes(legacyScopes))
+	}
+
+	if got := commandScopes(findCommand(t, root, "search")); len(got) != 0 {
+		t.Errorf("search needs no scope, got %v", got)
+	}
+	now := findCommand(t, root, "now")
+	if got := commandScopes(now); !reflect.DeepEqual(got, []string{spotify.ScopeUserReadCurrentlyPlaying}) {
+		t.Errorf("got scopes %v for now", got)
+	}
+
+	// a token granted only playlist scopes can't read the player
+	token := withScopes(&oauth2.Token{AccessToken: "access"}, playlistModifyScopes)
+	missing := missingScopes(tokenScopes(token), commandScopes(now))
+	if !reflect.DeepEqual(missing, []string{spotify.ScopeUserReadCurrentlyPlaying}) {
+		t.Fatalf("got missing scopes %v", missing)
+	}
+	if msg := missingScopesMessage(now, missing); !strings.Contains(msg, "now command needs the scope user-read-currently-playing") {
+		t.Errorf("unexpected message %q", msg)
+	}
+	rm := findCommand(t, root, "rm")
+	if missing := missingScopes(tokenScopes(token), commandScopes(rm)); len(missing) != 0 {
+		t.Errorf("got missing scopes %v for rm", missing)
+	}
+
+	// tokens that don't record scopes got the ones requested back then
+	if got := tokenScopes(&oauth2.Token{AccessToken: "access"}); !reflect.DeepEqual(got, legacyScopes) {
+		t.Errorf("got %v for a token without scopes, want %v", got, legacyScopes)
+	}
+}
+
+func TestUnionScopes(t *testing.T) {
+	got := unionScopes([]string{"b", "a"}, []string{"c", "a"})
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
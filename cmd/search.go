@@ -2,11 +2,7 @@ package cmd
 
 import (
 	"errors"
-	"fmt"
-	"strconv"
-	"strings"
 
-	"github.com/bndr/gotabulate"
 	"github.com/spf13/cobra"
 	"github.com/zmb3/spotify"
 )
@@ -18,14 +14,16 @@ var (
 
 func newSearchCmd() *cobra.Command {
 	searchCmd := &cobra.Command{
-		Use:   "search --t [SEARCH_TYPE] --q [SEARCH_QUERY]",
-		Short: "search tracks, albums, artists, playlists by name",
+		Use:         "search --t [SEARCH_TYPE] --q [SEARCH_QUERY]",
+		Short:       "search tracks, albums, artists, playlists by name",
+		Annotations: catalogCommand(),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return search(cmd, args)
 		},
 	}
 	searchCmd.Flags().StringVar(&searchType, "t", "", "The search type (tr, al, ar, pl).")
 	searchCmd.Flags().StringVar(&searchQuery, "q", "", "The search query term.")
+	searchCmd.Flags().BoolVar(&appOnly, "app-only", false, "Search as the application, without a user (default when not logged in and the client secret is set).")
 	return searchCmd
 }
 
@@ -45,155 +43,109 @@ func search(cmd *cobra.Command, args []string) error {
 }
 
 func displaySearchTracks(query string) error {
-	// get formatted track results and pretty print
-	data, err := searchTracks(query)
+	// get track results and render
+	records, err := searchTracks(query)
 	if err != nil {
 		return err
 	}
-	printSimple([]string{"ID", "Name", "Album", "Artist", "Popularity"}, data)
-	return nil
+	return render(trackView, records)
 }
 
 func displaySearchAlbums(query string) error {
-	// get formatted album results and pretty print
-	data, err := searchAlbums(query)
+	// get album results and render
+	records, err := searchAlbums(query)
 	if err != nil {
 		return err
 	}
-	printSimple([]string{"ID", "Name", "Artist", "Type", "Endpoint"}, data)
-	return nil
+	return render(albumView, records)
 }
 
 func displaySearchArtists(query string) error {
-	// get formatted artist results and pretty print
-	data, err := searchArtists(query)
+	// get artist results and render
+	records, err := searchArtists(query)
 	if err != nil {
 		return err
 	}
-	printSimple([]string{"ID", "Name", "Genres", "Followers", "Endpoint"}, data)
-	return nil
+	return render(artistView, records)
 }
 
 func displaySearchPlaylists(query string) error {
-	// get formatted playlist results and pretty print
-	data, err := searchPlaylists(query)
+	// get playlist results and render
+	records, err := searchPlaylists(query)
 	if err != nil {
 		return err
 	}
-	printSimple([]string{"ID", "Name", "Owner", "Total Tracks", "Endpoint"}, data)
-	return nil
+	return render(playlistSearchView, records)
 }
 
-func searchTracks(query string) ([][]interface{}, error) {
-	results, err := client.Search(query, spotify.SearchTypeTrack)
+func searchTracks(query string) ([]trackRecord, error) {
+	results, err := searchMarket(query, spotify.SearchTypeTrack)
 	if err != nil {
 		return nil, err
 	}
 
 	// iterate over tracks from query results
-	var data [][]interface{}
+	var records []trackRecord
 	if results.Tracks != nil {
 		for _, item := range results.Tracks.Tracks {
-			track := []string{
-				string(item.ID),
-				item.Name,
-				item.Album.Name,
-				item.Artists[0].Name,
-				strconv.Itoa(item.Popularity),
-			}
-			row := make([]interface{}, len(track))
-			for i, d := range track {
-				row[i] = d
-			}
-			data = append(data, row)
+			records = append(records, newTrackRecord(item))
 		}
 	}
-	return data, nil
+	return records, nil
 }
 
-func searchAlbums(query string) ([][]interface{}, error) {
-	results, err := client.Search(query, spotify.SearchTypeAlbum)
+func searchAlbums(query string) ([]albumRecord, error) {
+	results, err := searchMarket(query, spotify.SearchTypeAlbum)
 	if err != nil {
 		return nil, err
 	}
 
 	// iterate over albums from query results
-	var data [][]interface{}
+	var records []albumRecord
 	if results.Albums != nil {
 		for _, item := range results.Albums.Albums {
-			album := []string{
-				string(item.ID),
-				item.Name,
-				item.Artists[0].Name,
-				item.AlbumType,
-				item.Endpoint,
-			}
-			row := make([]interface{}, len(album))
-			for i, d := range album {
-				row[i] = d
-			}
-			data = append(data, row)
+			records = append(records, newAlbumRecord(item))
 		}
 	}
-	return data, nil
+	return records, nil
 }
 
-func searchArtists(query string) ([][]interface{}, error) {
-	results, err := client.Search(query, spotify.SearchTypeArtist)
+func searchArtists(query string) ([]artistRecord, error) {
+	results, err := searchMarket(query, spotify.SearchTypeArtist)
 	if err != nil {
 		return nil, err
 	}
 
 	// iterate over artists from query results
-	var data [][]interface{}
+	var records []artistRecord
 	if results.Artists != nil {
 		for _, item := range results.Artists.Artists {
-			artist := []string{
-				string(item.ID),
-				item.Name,
-				strings.Join(item.Genres, ","),
-				strconv.Itoa(int(item.Followers.Count)),
-				item.Endpoint,
-			}
-			row := make([]interface{}, len(artist))
-			for i, d := range artist {
-				row[i] = d
-			}
-			data = append(data, row)
+			records = append(records, newArtistRecord(item))
 		}
 	}
-	return data, nil
+	return records, nil
 }
 
-func searchPlaylists(query string) ([][]interface{}, error) {
-	results, err := client.Search(query, spotify.SearchTypePlaylist)
+func searchPlaylists(query string) ([]playlistRecord, error) {
+	results, err := searchMarket(query, spotify.SearchTypePlaylist)
 	if err != nil {
 		return nil, err
 	}
 
 	// iterate over playlists from query results
-	var data [][]interface{}
+	var records []playlistRecord
 	if results.Playlists != nil {
 		for _, item := range results.Playlists.Playlists {
-			playlist := []string{
-				string(item.ID),
-				item.Name,
-				item.Owner.DisplayName,
-				strconv.Itoa(int(item.Tracks.Total)),
-				item.Endpoint,
-			}
-			row := make([]interface{}, len(playlist))
-			for i, d := range playlist {
-				row[i] = d
-			}
-			data = append(data, row)
+			records = append(records, newPlaylistRecord(item))
 		}
 	}
-	return data, nil
+	return records, nil
 }
 
-func printSimple(headers []string, data [][]interface{}) {
-	tabulate := gotabulate.Create(data)
-	tabulate.SetHeaders(headers)
-	fmt.Println(tabulate.Render("simple"))
+// searchMarket runs query, in the market of the profile if it has one.
+func searchMarket(query string, t spotify.SearchType) (*spotify.SearchResult, error) {
+	if market == "" {
+		return client.Search(query, t)
+	}
+	return client.SearchOpt(query, t, &spotify.Options{Country: &market})
 }
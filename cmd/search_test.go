@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	srv := newTestServer(t)
+
+	tests := []struct {
+		typ   string
+		query string
+		want  string
+	}{
+		{"tr", "numb", "Numb - Live"},
+		{"al", "meteora", "al-Meteora"},
+		{"ar", "linkin", "alternative metal,nu metal"},
+		{"pl", "mix", "Mix"},
+	}
+	for _, test := range tests {
+		out, err := execute(t, srv, "search", "--t", test.typ, "--q", test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, test.want) {
+			t.Errorf("search %s %q: expected %q in output:\n%s", test.typ, test.query, test.want, out)
+		}
+	}
+
+	if _, err := execute(t, srv, "search", "--t", "xx", "--q", "numb"); err == nil {
+		t.Error("expected error for unsupported search type")
+	}
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	shufflePlaylistName string
+	shuffleSeed         int64
+	shuffleArtistGap    int
+	shuffleSpreadAlbums bool
+)
+
+func newShufflePlaylistCmd() *cobra.Command {
+	shuffleCmd := &cobra.Command{
+		Use:         "shuffle --p [PLAYLIST_NAME]",
+		Short:       "Shuffle the stored order of a playlist",
+		Annotations: needsScopes(playlistModifyScopes...),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return shufflePlaylist(cmd, args)
+		},
+	}
+	shuffleCmd.Flags().StringVar(&shufflePlaylistName, "p", "", "Name, ID, URI or URL of playlist to shuffle.")
+	shuffleCmd.Flags().Int64Var(&shuffleSeed, "seed", 0, "Seed of the shuffle, to repeat an earlier one (default random).")
+	shuffleCmd.Flags().IntVar(&shuffleArtistGap, "artist-gap", 0, "Keep at least this many tracks between tracks of the same artist.")
+	shuffleCmd.Flags().BoolVar(&shuffleSpreadAlbums, "spread-albums", false, "Spread the tracks of each album evenly over the playlist.")
+	addPlaylistSelectorFlags(shuffleCmd.Flags())
+	return shuffleCmd
+}
+
+func shufflePlaylist(cmd *cobra.Command, args []string) error {
+	if shuffleArtistGap < 0 {
+		return errors.New("--artist-gap must not be negative")
+	}
+	seed := shuffleSeed
+	if !cmd.Flags().Changed("seed") {
+		seed = time.Now().UnixNano()
+	}
+
+	// current user
+	user, err := client.CurrentUser()
+	if err != nil {
+		return err
+	}
+	printInfo("User: ", user.DisplayName)
+
+	// get the playlist and its tracks
+	pl, err := resolvePlaylist(shufflePlaylistName)
+	if err != nil {
+		return err
+	}
+	printInfo("Playlist: ", pl.Name)
+	entries, err := readPlaylistEntries(user.ID, pl.ID)
+	if err != nil {
+		return err
+	}
+
+	// shuffle, then write the new order back
+	order, violations := shuffleOrder(entries, rand.New(rand.NewSource(seed)), shuffleSpreadAlbums, shuffleArtistGap)
+	if violations > 0 {
+		fmt.Fprintf(stderr, "Warning: %d tracks have fewer than %d tracks between them and the previous one by the same artist.\n", violations, shuffleArtistGap)
+	}
+	requests, err := applyOrder(user.ID, pl, entries, order, true)
+	if err != nil {
+		return err
+	}
+	if requests == 0 {
+		printInfof("Playlist \"%s\" is already in the order seed %d gives.\n", pl.Name, seed)
+		return nil
+	}
+	printInfof("Shuffled playlist \"%s\" with seed %d.\n", pl.Name, seed)
+	return nil
+}
+
+// shuffleOrder returns a random order of entries, as their current
+// positions, and the number of tracks that could not be kept artistGap
+// tracks away from the same artist. With spreadAlbums, the tracks of each
+// album are spread evenly.
+func shuffleOrder(entries []playlistEntry, rng *rand.Rand, spreadAlbums bool, artistGap int) ([]int, int) {
+	var order []int
+	if spreadAlbums {
+		order = spreadOrder(entries, rng, albumKey)
+	} else {
+		order = rng.Perm(len(entries))
+	}
+	if artistGap == 0 {
+		return order, 0
+	}
+	return keepArtistsApart(entries, order, artistGap)
+}
+
+// spreadOrder shuffles entries so that the ones in the same group are
+// spread evenly: each group is laid out at regular intervals from a random
+// offset, with some jitter, and the groups are then interleaved.
+func spreadOrder(entries []playlistEntry, rng *rand.Rand, group func(playlistEntry) string) []int {
+	groups := make(map[string][]int)
+	var keys []string
+	for i, e := range entries {
+		key := group(e)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+	// a stable order, so a seed always gives the same shuffle
+	sort.Strings(keys)
+
+	at := make([]float64, len(entries))
+	for _, key := range keys {
+		members := groups[key]
+		rng.Shuffle(len(members), func(i, j int) { members[i], members[j] = members[j], members[i] })
+		interval := float64(len(entries)) / float64(len(members))
+		offset := rng.Float64() * interval
+		for i, pos := range members {
+			at[pos] = offset + float64(i)*interval + (rng.Float64()-0.5)*interval/5
+		}
+	}
+
+	order := rng.Perm(len(entries))
+	sort.SliceStable(order, func(i, j int) bool { return at[order[i]] < at[order[j]] })
+	return order
+}
+
+// keepArtistsApart reorders order so that gap tracks at least separate
+// tracks of the same artist, taking each time the first remaining track
+// not by one of the last gap artists. When there is none, it takes the
+// first one anyway and counts a violation.
+func keepArtistsApart(entries []playlistEntry, order []int, gap int) ([]int, int) {
+	artist := func(pos int) string { return artistKey(entries[pos]) }
+
+	remaining := append([]int(nil), order...)
+	result := make([]int, 0, len(order))
+	violations := 0
+	for len(remaining) > 0 {
+		recent := make(map[string]bool)
+		for i := len(result) - 1; i >= 0 && i >= len(result)-gap; i-- {
+			recent[artist(result[i])] = true
+		}
+
+		pick := -1
+		for i, pos := range remaining {
+			if !recent[artist(pos)] {
+				pick = i
+				break
+			}
+		}
+		if pick < 0 {
+			pick = 0
+			violations++
+		}
+		result = append(result, remaining[pick])
+		remaining = append(remaining[:pick], remaining[pick+1:]...)
+	}
+	return result, violations
+}
+
+// albumKey identifies the album of e by its ID, or by its name for local
+// files, which have no IDs.
+func albumKey(e playlistEntry) string {
+	if id := e.track.Album.ID; id != "" {
+		return string(id)
+	}
+	return "local:" + strings.ToLower(e.track.Album.Name)
+}
+
+// artistKey identifies the first artist of e like albumKey does its album.
+func artistKey(e playlistEntry) string {
+	if len(e.track.Artists) > 0 && e.track.Artists[0].ID != "" {
+		return string(e.track.Artists[0].ID)
+	}
+	return "local:" + strings.ToLower(firstOf(artistNames(e.track.Artists)))
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/zmb3/spotify"
+)
+
+// testEntries returns entries by the given artists, on albums of two tracks.
+func testEntries(artists ...string) []playlistEntry {
+	entries := make([]playlistEntry, len(artists))
+	for i, artist := range artists {
+		track := testTrack(fmt.Sprint("e", i), fmt.Sprint("Song ", i), artist, fmt.Sprint(artist, " album ", i/2), 200000, 10)
+		entries[i] = playlistEntry{position: i, track: track}
+	}
+	return entries
+}
+
+func TestShuffleOrder(t *testing.T) {
+	entries := testEntries("A", "A", "A", "A", "B", "B", "B", "C", "C", "D")
+	for seed := int64(0); seed < 100; seed++ {
+		order, violations := shuffleOrder(entries, rand.New(rand.NewSource(seed)), seed%2 == 0, 1)
+
+		// every track once
+		sorted := append([]int(nil), order...)
+		sort.Ints(sorted)
+		for i, pos := range sorted {
+			if i != pos {
+				t.Fatalf("seed %d: %v is not a permutation", seed, order)
+			}
+		}
+
+		// no artist twice in a row, unless reported
+		adjacent := 0
+		for i := 1; i < len(order); i++ {
+			if entries[order[i]].track.Artists[0].Name == entries[order[i-1]].track.Artists[0].Name {
+				adjacent++
+			}
+		}
+		if adjacent != violations {
+			t.Errorf("seed %d: %d tracks next to the same artist, %d reported", seed, adjacent, violations)
+		}
+	}
+
+	// the same seed gives the same order
+	first, _ := shuffleOrder(entries, rand.New(rand.NewSource(7)), true, 2)
+	again, _ := shuffleOrder(entries, rand.New(rand.NewSource(7)), true, 2)
+	if !reflect.DeepEqual(first, again) {
+		t.Errorf("seed 7 gave %v, then %v", first, again)
+	}
+}
+
+func TestShuffleKeys(t *testing.T) {
+	// different artists sharing a name are told apart by ID
+	entries := testEntries("Nirvana", "Nirvana")
+	entries[1].track.Artists[0].ID = "ar-Nirvana1960s"
+	if _, violations := keepArtistsApart(entries, []int{0, 1}, 1); violations != 0 {
+		t.Errorf("got %d violations for different artists sharing a name", violations)
+	}
+
+	// local files only have names
+	for i := range entries {
+		entries[i].track.ID, entries[i].track.Artists[0].ID, entries[i].track.Album.ID = "", "", ""
+	}
+	if _, violations := keepArtistsApart(entries, []int{0, 1}, 1); violations != 1 {
+		t.Errorf("got %d violations for local files by the same artist, want 1", violations)
+	}
+	if albumKey(entries[0]) != albumKey(entries[1]) {
+		t.Error("expected local files named alike to share their album")
+	}
+}
+
+func TestShufflePlaylist(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddTrack(testTrack("t400000000000000000000", "One", "U2", "Achtung Baby", 276000, 60))
+	srv.AddTrack(testTrack("t500000000000000000000", "Beautiful Day", "U2", "All That You Can't Leave Behind", 248000, 60))
+
+	var orders [][]spotify.ID
+	for i := 0; i < 2; i++ {
+		id := srv.AddPlaylist(spotify.SimplePlaylist{Name: fmt.Sprint("Shuffle ", i)}, "t100000000000000000000", "t200000000000000000000", "t300000000000000000000", "t400000000000000000000", "t500000000000000000000")
+		if _, err := execute(t, srv, "shuffle", "--p", string(id), "--seed", "42", "--artist-gap", "1"); err != nil {
+			t.Fatal(err)
+		}
+		orders = append(orders, srv.PlaylistTracks(id))
+	}
+	if !reflect.DeepEqual(orders[0], orders[1]) {
+		t.Errorf("the same seed gave %v, then %v", orders[0], orders[1])
+	}
+
+	// three Linkin Park tracks and two U2 ones alternate
+	want := []bool{false, true, false, true, false}
+	var got []bool
+	for _, id := range orders[0] {
+		got = append(got, id == "t400000000000000000000" || id == "t500000000000000000000")
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want the artists alternating", orders[0])
+	}
+}
+
+func TestShuffleLargePlaylist(t *testing.T) {
+	srv := newTestServer(t)
+	var ids []spotify.ID
+	for i := 0; i < 150; i++ {
+		id := spotify.ID(fmt.Sprintf("s%021d", i))
+		srv.AddTrack(testTrack(string(id), string(id), fmt.Sprint("Artist ", i%7), "Album", 200000, 10))
+		ids = append(ids, id)
+	}
+	id := srv.AddPlaylist(spotify.SimplePlaylist{Name: "Large"}, ids...)
+
+	if _, err := execute(t, srv, "shuffle", "--p", "Large", "--seed", "1", "--artist-gap", "2"); err != nil {
+		t.Fatal(err)
+	}
+	shuffled := srv.PlaylistTracks(id)
+	if reflect.DeepEqual(shuffled, ids) {
+		t.Error("expected the playlist to be shuffled")
+	}
+	// every track is written back, past the first 100 too
+	got := append([]spotify.ID(nil), shuffled...)
+	sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+	if !reflect.DeepEqual(got, ids) {
+		t.Errorf("got tracks %v, want the same tracks shuffled", shuffled)
+	}
+
+	// a single track has nowhere to go
+	srv.AddPlaylist(spotify.SimplePlaylist{Name: "Single"}, "s000000000000000000000")
+	out, err := execute(t, srv, "shuffle", "--p", "Single", "--seed", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "Shuffled") {
+		t.Errorf("expected the playlist to be left as is:\n%s", out)
+	}
+}
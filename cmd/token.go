@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// grantPKCE marks tokens obtained with the Authorization Code with PKCE
+// flow, which are refreshed without the client secret.
+const grantPKCE = "pkce"
+
+// storedToken is the stored form of a token. It keeps the granted scopes and
+// the grant the token was obtained with, which oauth2.Token only holds in
+// its extra fields.
+type storedToken struct {
+	oauth2.Token
+	Scope string `json:"scope,omitempty"`
+	Grant string `json:"grant,omitempty"`
+}
+
+// grantedScopes returns the scopes granted to token.
+func grantedScopes(token *oauth2.Token) []string {
+	scope, _ := token.Extra("scope").(string)
+	return strings.Fields(scope)
+}
+
+// tokenGrant returns the grant token was obtained with: grantPKCE, or empty
+// for the Authorization Code flow with the client secret.
+func tokenGrant(token *oauth2.Token) string {
+	grant, _ := token.Extra("grant").(string)
+	return grant
+}
+
+// withScopes returns token holding scopes as the granted ones.
+func withScopes(token *oauth2.Token, scopes []string) *oauth2.Token {
+	return withExtras(token, scopes, tokenGrant(token))
+}
+
+// withGrant returns token marked as obtained with grant.
+func withGrant(token *oauth2.Token, grant string) *oauth2.Token {
+	return withExtras(token, grantedScopes(token), grant)
+}
+
+func withExtras(token *oauth2.Token, scopes []string, grant string) *oauth2.Token {
+	extra := map[string]interface{}{"scope": strings.Join(scopes, " ")}
+	if grant != "" {
+		extra["grant"] = grant
+	}
+	return token.WithExtra(extra)
+}
+
+// tokenStore persists the OAuth2 token of the user in a file.
+type tokenStore struct {
+	path string
+}
+
+// newTokenStore returns the store for the token of profile in the config
+// directory. A token left in ~/.sptok by earlier versions is moved to the
+// default profile.
+func newTokenStore(profile string) (*tokenStore, error) {
+	path, err := tokenPath(profile)
+	if err != nil {
+		return nil, err
+	}
+	s := &tokenStore{path: path}
+	if profile != defaultProfile {
+		return s, nil
+	}
+
+	home, err := homeDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.migrate(filepath.Join(home, legacyTokenFile)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// tokenPath returns the path of the token of profile. The tokens of named
+// profiles are kept in a directory of their own under profiles.
+func tokenPath(profile string) (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	if profile == defaultProfile {
+		return filepath.Join(dir, tokenFile), nil
+	}
+	if err := checkProfileName(profile); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "profiles", profile, tokenFile), nil
+}
+
+// migrate moves the token at legacy into the store, unless the store already
+// holds one.
+func (s *tokenStore) migrate(legacy string) error {
+	if _, err := os.Stat(legacy); os.IsNotExist(err) {
+		return nil
+	}
+	if _, err := os.Stat(s.path); err == nil {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(legacy)
+	if err != nil {
+		return err
+	}
+	var token oauth2.Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return fmt.Errorf("%s: %v", legacy, err)
+	}
+	if err := s.Save(&token); err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "Moved token from %s to %s\n", legacy, s.path)
+	return os.Remove(legacy)
+}
+
+// Load reads the stored token. The error satisfies os.IsNotExist when no
+// token was saved yet.
+func (s *tokenStore) Load() (*oauth2.Token, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	warnIfTooOpen(s.path)
+
+	var st storedToken
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+	return withExtras(&st.Token, strings.Fields(st.Scope), st.Grant), nil
+}
+
+// Save writes the token, replacing any stored one. It writes a temporary
+// file next to the token file and renames it over, so an interrupted save
+// never leaves a truncated token behind. The token is only readable by the
+// user.
+func (s *tokenStore) Save(token *oauth2.Token) error {
+	st := storedToken{Token: *token, Scope: strings.Join(grantedScopes(token), " "), Grant: tokenGrant(token)}
+	b, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, b)
+}
+
+// writeFileAtomic writes data to a temporary file next to path, only
+// readable by the user, and renames it over path.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	// TempFile creates the file with 0600 already, but be explicit
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// Delete removes the stored token.
+func (s *tokenStore) Delete() error {
+	return os.Remove(s.path)
+}
+
+// savingTokenSource is an oauth2.TokenSource that writes every new token
+// obtained from src, such as the result of a refresh, to store.
+type savingTokenSource struct {
+	src   oauth2.TokenSource
+	store *tokenStore
+
+	mu   sync.Mutex
+	last *oauth2.Token
+}
+
+// newSavingTokenSource returns a token source refreshing token through
+// config and persisting refreshed tokens to store. Tokens obtained with PKCE
+// are refreshed the same way.
+func newSavingTokenSource(config *oauth2.Config, token *oauth2.Token, store *tokenStore) *savingTokenSource {
+	src := config.TokenSource(oauthContext(), token)
+	if tokenGrant(token) == grantPKCE {
+		src = oauth2.ReuseTokenSource(token, &pkceRefresher{config: config, refreshToken: token.RefreshToken})
+	}
+	return &savingTokenSource{
+		src:   src,
+		store: store,
+		last:  token,
+	}
+}
+
+// Token implements oauth2.TokenSource.
+func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.src.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == nil || token.AccessToken != s.last.AccessToken {
+		// a refresh response may leave out the unchanged scopes, and never
+		// tells the grant
+		if s.last != nil {
+			if len(grantedScopes(token)) == 0 {
+				token = withScopes(token, grantedScopes(s.last))
+			}
+			token = withGrant(token, tokenGrant(s.last))
+		}
+		if err := s.store.Save(token); err != nil {
+			return nil, err
+		}
+		s.last = token
+	}
+	return token, nil
+}
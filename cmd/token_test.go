@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestTokenStoreOverwrites(t *testing.T) {
+	dir := t.TempDir()
+	store := &tokenStore{path: filepath.Join(dir, tokenFile)}
+
+	if _, err := store.Load(); !os.IsNotExist(err) {
+		t.Fatalf("expected not exist error, got %v", err)
+	}
+	for _, access := range []string{"first", "second"} {
+		if err := store.Save(&oauth2.Token{AccessToken: access, RefreshToken: "refresh"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "second" {
+		t.Errorf("got access token %q, want the last saved one", token.AccessToken)
+	}
+
+	// no temporary files are left behind
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected only the token file, got %d files", len(files))
+	}
+
+	if err := store.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(); !os.IsNotExist(err) {
+		t.Errorf("expected token to be deleted, got %v", err)
+	}
+}
+
+// sequenceTokenSource returns its tokens in turn, as successive refreshes
+// would.
+type sequenceTokenSource []*oauth2.Token
+
+func (s *sequenceTokenSource) Token() (*oauth2.Token, error) {
+	token := (*s)[0]
+	*s = (*s)[1:]
+	return token, nil
+}
+
+func TestSavingTokenSourcePersistsRefreshes(t *testing.T) {
+	store := &tokenStore{path: filepath.Join(t.TempDir(), tokenFile)}
+	initial := &oauth2.Token{AccessToken: "old", Expiry: time.Now().Add(-time.Hour)}
+	refreshed := &oauth2.Token{AccessToken: "new", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour)}
+	src := &savingTokenSource{
+		src:   &sequenceTokenSource{initial, refreshed, refreshed},
+		store: store,
+		last:  initial,
+	}
+
+	// the initial token is not written back
+	if _, err := src.Token(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(); !os.IsNotExist(err) {
+		t.Fatalf("unchanged token was saved: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := src.Token(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "new" || token.RefreshToken != "refresh" {
+		t.Errorf("got stored token %+v", token)
+	}
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify"
+)
+
+// defaultMinScore is the score from which a search result is taken as the
+// track a line of a tracklist means.
+const defaultMinScore = 0.8
+
+// durationPattern matches a duration noted at the end of a tracklist line,
+// such as "(3:05)" or "[3:05]".
+var durationPattern = regexp.MustCompile(`\s*[(\[](\d+):(\d{2})[)\]]$`)
+
+// tracklistEntry is a line of a tracklist: "Artist - Title", optionally
+// followed by the duration.
+type tracklistEntry struct {
+	line
+	artist   string
+	title    string
+	duration time.Duration
+}
+
+// parseTracklistEntry parses l. Lines without " - " are taken as a title.
+func parseTracklistEntry(l line) tracklistEntry {
+	e := tracklistEntry{line: l}
+	text := l.text
+	if m := durationPattern.FindStringSubmatch(text); m != nil {
+		min, _ := strconv.Atoi(m[1])
+		sec, _ := strconv.Atoi(m[2])
+		e.duration = time.Duration(min)*time.Minute + time.Duration(sec)*time.Second
+		text = text[:len(text)-len(m[0])]
+	}
+	for _, sep := range []string{" - ", " – ", " — "} {
+		if i := strings.Index(text, sep); i >= 0 {
+			e.artist, e.title = strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+len(sep):])
+			return e
+		}
+	}
+	e.title = strings.TrimSpace(text)
+	return e
+}
+
+// query returns the search query for e, using field filters.
+func (e tracklistEntry) query() string {
+	q := "track:" + quoteFilter(e.title)
+	if e.artist != "" {
+		q += " artist:" + quoteFilter(e.artist)
+	}
+	return q
+}
+
+// quoteFilter quotes the value of a search field filter. The search syntax
+// has no escapes, so double quotes inside the value are dropped.
+func quoteFilter(s string) string {
+	return `"` + strings.Replace(s, `"`, "", -1) + `"`
+}
+
+// score rates how likely t is the track e means, from 0 to 1, by the
+// similarity of the title and artist, and of the duration when e has one.
+func (e tracklistEntry) score(t spotify.FullTrack) float64 {
+	title := similarity(e.title, t.Name)
+	if e.artist == "" && e.duration == 0 {
+		return title
+	}
+
+	artist := 0.0
+	for _, name := range artistNames(t.Artists) {
+		if s := similarity(e.artist, name); s > artist {
+			artist = s
+		}
+	}
+	if e.duration == 0 {
+		return 0.6*title + 0.4*artist
+	}
+
+	// full marks within 3 seconds, none from 30 seconds off
+	diff := e.duration - time.Duration(t.Duration)*time.Millisecond
+	if diff < 0 {
+		diff = -diff
+	}
+	duration := 1 - float64(diff-3*time.Second)/float64(27*time.Second)
+	if duration > 1 {
+		duration = 1
+	} else if duration < 0 {
+		duration = 0
+	}
+	if e.artist == "" {
+		return 0.8*title + 0.2*duration
+	}
+	return 0.5*title + 0.3*artist + 0.2*duration
+}
+
+// tracklistMatch is the best search result for a tracklist entry.
+type tracklistMatch struct {
+	entry tracklistEntry
+	track *spotify.FullTrack
+	score float64
+}
+
+// matchTracklistEntry searches for the track e means and returns the best
+// scored result, more popular ones first on equal scores.
+func matchTracklistEntry(e tracklistEntry) (tracklistMatch, error) {
+	m := tracklistMatch{entry: e}
+	results, err := searchMarket(e.query(), spotify.SearchTypeTrack)
+	if err != nil {
+		return m, err
+	}
+	if results.Tracks == nil {
+		return m, nil
+	}
+
+	tracks := results.Tracks.Tracks
+	sort.SliceStable(tracks, func(i, j int) bool { return tracks[i].Popularity > tracks[j].Popularity })
+	for i := range tracks {
+		if s := e.score(tracks[i]); m.track == nil || s > m.score {
+			m.track, m.score = &tracks[i], s
+		}
+	}
+	return m, nil
+}
+
+// addTracklistFromFile adds the tracks listed as "Artist - Title" in path
+// to the playlist. Lines without a confident match are written to the
+// report for review.
+func addTracklistFromFile(user *spotify.PrivateUser, pl spotify.SimplePlaylist, path, reportPath string, minScore float64) error {
+	lines, err := readLines(path)
+	if err != nil {
+		return err
+	}
+
+	// match every line, keeping the confident ones
+	var confident []*spotify.FullTrack
+	var review []tracklistMatch
+	for _, l := range lines {
+		m, err := matchTracklistEntry(parseTracklistEntry(l))
+		if err != nil {
+			return fmt.Errorf("line %d: %v", l.number, err)
+		}
+		if m.track == nil || m.score < minScore {
+			review = append(review, m)
+			continue
+		}
+		printInfof("Track: %s - %s (%.2f)\n", firstOf(artistNames(m.track.Artists)), m.track.Name, m.score)
+		confident = append(confident, m.track)
+	}
+
+	// add the ones not in the playlist yet
+	if confident, err = skipPresent(pl, newPlaylistTrackIDs(user.ID, pl.ID), confident); err != nil {
+		return err
+	}
+	failures := 0
+	added := addTracksInChunks(user.ID, pl.ID, confident, func(t *spotify.FullTrack, err error) {
+		failures++
+		fmt.Fprintf(stderr, "%s: %v\n", t.Name, err)
+	})
+	printInfof("Added %d of %d tracks to playlist \"%s\".\n", added, len(lines), pl.Name)
+
+	// report the rest
+	if len(review) > 0 {
+		report := formatTracklistReport(review)
+		if reportPath == "" {
+			io.WriteString(stderr, report)
+		} else {
+			if err := ioutil.WriteFile(reportPath, []byte(report), 0644); err != nil {
+				return err
+			}
+			printInfof("%d lines to review in %s.\n", len(review), reportPath)
+		}
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d tracks could not be added", failures)
+	}
+	return nil
+}
+
+// formatTracklistReport lists the lines without a confident match, with
+// the best candidate if any.
+func formatTracklistReport(review []tracklistMatch) string {
+	var b strings.Builder
+	for _, m := range review {
+		if m.track == nil {
+			fmt.Fprintf(&b, "line %d: %s: no match\n", m.entry.number, m.entry.text)
+			continue
+		}
+		duration := (time.Duration(m.track.Duration) * time.Millisecond).Truncate(time.Second)
+		fmt.Fprintf(&b, "line %d: %s: low confidence %.2f, best: %s - %s (%s, %s) %s\n",
+			m.entry.number, m.entry.text, m.score, firstOf(artistNames(m.track.Artists)), m.track.Name, m.track.Album.Name, duration, m.track.URI)
+	}
+	return b.String()
+}
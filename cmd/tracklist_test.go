@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify"
+)
+
+func TestParseTracklistEntry(t *testing.T) {
+	tests := []struct {
+		text     string
+		artist   string
+		title    string
+		duration time.Duration
+		query    string
+	}{
+		{"Linkin Park - Numb", "Linkin Park", "Numb", 0, `track:"Numb" artist:"Linkin Park"`},
+		{"Linkin Park – Numb (3:05)", "Linkin Park", "Numb", 3*time.Minute + 5*time.Second, `track:"Numb" artist:"Linkin Park"`},
+		{"Jay-Z - 99 Problems [4:15]", "Jay-Z", "99 Problems", 4*time.Minute + 15*time.Second, `track:"99 Problems" artist:"Jay-Z"`},
+		{"Numb", "", "Numb", 0, `track:"Numb"`},
+		{`AC\DC - "Thunderstruck"`, `AC\DC`, `"Thunderstruck"`, 0, `track:"Thunderstruck" artist:"AC\DC"`},
+	}
+	for _, test := range tests {
+		e := parseTracklistEntry(line{1, test.text})
+		if e.artist != test.artist || e.title != test.title || e.duration != test.duration {
+			t.Errorf("%q: got %q, %q, %v, want %q, %q, %v", test.text, e.artist, e.title, e.duration, test.artist, test.title, test.duration)
+		}
+		if q := e.query(); q != test.query {
+			t.Errorf("%q: got query %s, want %s", test.text, q, test.query)
+		}
+	}
+}
+
+func TestAddTracklistFromFile(t *testing.T) {
+	srv := newTestServer(t)
+	id := srv.AddPlaylist(spotify.SimplePlaylist{Name: "Bulk"})
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "list.txt")
+	list := "# tonight\nLinkin Park - Numb (3:05)\nlinkin park - faint\nNobody - Nothing\nLinkin Park - Num (5:00)\n"
+	if err := ioutil.WriteFile(path, []byte(list), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := execute(t, srv, "add", "--from-file", path, "--p", "Bulk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Added 2 of 4 tracks") {
+		t.Errorf("expected a summary in output:\n%s", out)
+	}
+	if want := []spotify.ID{"t100000000000000000000", "t200000000000000000000"}; !reflect.DeepEqual(srv.PlaylistTracks(id), want) {
+		t.Errorf("got tracks %v, want %v", srv.PlaylistTracks(id), want)
+	}
+
+	// the rest is left for review
+	report, err := ioutil.ReadFile(path + ".report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"line 4: Nobody - Nothing: no match",
+		"line 5: Linkin Park - Num (5:00): low confidence",
+	} {
+		if !strings.Contains(string(report), want) {
+			t.Errorf("report does not contain %q:\n%s", want, report)
+		}
+	}
+}
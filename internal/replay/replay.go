@@ -0,0 +1,242 @@
+// Package replay records HTTP exchanges with the Spotify Web API into fixture
+// files and replays them, so tests can run against real responses without
+// network access.
+//
+// A Recorder wraps the transport under the oauth2 client. Saving scrubs the
+// exchanges: request headers are dropped, tokens, credentials and personal
+// data are redacted, and user IDs in request URLs and in the id, uri and href
+// fields of bodies are replaced by stable placeholders (user1, user2, ...).
+// A Replayer loaded from the file then answers the same requests.
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// Fixture is the on-disk form of a recording.
+type Fixture struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request. Headers are never recorded since they carry
+// credentials.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is a recorded response. JSON bodies are kept as JSON so fixtures
+// stay readable; anything else is kept as text.
+type Response struct {
+	Status      int             `json:"status"`
+	ContentType string          `json:"content_type,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"`
+	Text        string          `json:"text,omitempty"`
+}
+
+// Recorder is an http.RoundTripper that captures every exchange made through
+// it.
+type Recorder struct {
+	// Transport makes the actual requests. http.DefaultTransport is used
+	// when nil.
+	Transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+// NewRecorder returns a Recorder sending requests through transport.
+func NewRecorder(transport http.RoundTripper) *Recorder {
+	return &Recorder{Transport: transport}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = b
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	in := Interaction{
+		Request: Request{Method: req.Method, URL: req.URL.String(), Body: string(reqBody)},
+		Response: Response{
+			Status:      resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+		},
+	}
+	if json.Valid(respBody) {
+		in.Response.Body = respBody
+	} else {
+		in.Response.Text = string(respBody)
+	}
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, in)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Fixture returns the scrubbed recording.
+func (r *Recorder) Fixture() (*Fixture, error) {
+	r.mu.Lock()
+	interactions := make([]Interaction, len(r.interactions))
+	copy(interactions, r.interactions)
+	r.mu.Unlock()
+
+	return scrub(interactions)
+}
+
+// Save writes the scrubbed recording to path.
+func (r *Recorder) Save(path string) error {
+	f, err := r.Fixture()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(f); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// Replayer is an http.RoundTripper answering requests from a recording.
+// Each interaction is used once; requests are matched on method, URL and
+// body in recording order, so repeated identical requests get successive
+// responses.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewReplayer returns a Replayer serving the interactions of f.
+func NewReplayer(f *Fixture) *Replayer {
+	return &Replayer{
+		interactions: f.Interactions,
+		used:         make([]bool, len(f.Interactions)),
+	}
+}
+
+// Load reads a fixture file written by Recorder.Save.
+func Load(path string) (*Replayer, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("replay: %s: %v", path, err)
+	}
+	return NewReplayer(&f), nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.interactions {
+		if r.used[i] || in.Request.Method != req.Method || in.Request.URL != req.URL.String() ||
+			!sameBody(in.Request.Body, redactForm(string(body))) {
+			continue
+		}
+		r.used[i] = true
+		return in.Response.build(req), nil
+	}
+	return nil, fmt.Errorf("replay: no recorded response for %s %s", req.Method, req.URL)
+}
+
+// Unused returns the requests that were recorded but never replayed.
+func (r *Replayer) Unused() []Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unused []Request
+	for i, in := range r.interactions {
+		if !r.used[i] {
+			unused = append(unused, in.Request)
+		}
+	}
+	return unused
+}
+
+func (resp Response) build(req *http.Request) *http.Response {
+	body := []byte(resp.Text)
+	if len(resp.Body) > 0 {
+		body = resp.Body
+	}
+	header := make(http.Header)
+	if resp.ContentType != "" {
+		header.Set("Content-Type", resp.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.Status, http.StatusText(resp.Status)),
+		StatusCode:    resp.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// sameBody compares request bodies, ignoring JSON formatting differences.
+func sameBody(recorded, actual string) bool {
+	if recorded == actual {
+		return true
+	}
+	var a, b interface{}
+	if json.Unmarshal([]byte(recorded), &a) != nil || json.Unmarshal([]byte(actual), &b) != nil {
+		return false
+	}
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Equal(ja, jb)
+}
@@ -0,0 +1,91 @@
+package replay
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/me":
+			w.Write([]byte(`{"id":"jdoe42","type":"user","email":"jdoe@example.com","display_name":"J"}`))
+		case "/v1/users/jdoe42/playlists":
+			w.Write([]byte(`{"items":[{"id":"p1","owner":{"id":"jdoe42","type":"user"}},{"id":"p2","name":"bobsleigh","owner":{"id":"bob","type":"user","uri":"spotify:user:bob","external_urls":{"spotify":"https://open.spotify.com/user/bob"}}}]}`))
+		case "/api/token":
+			w.Write([]byte(`{"access_token":"secret-access","refresh_token":"secret-refresh","token_type":"Bearer"}`))
+		}
+	}))
+	defer srv.Close()
+
+	rec := NewRecorder(nil)
+	hc := &http.Client{Transport: rec}
+	for _, path := range []string{"/v1/me", "/v1/users/jdoe42/playlists"} {
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer secret-access")
+		resp, err := hc.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	resp, err := hc.Post(srv.URL+"/api/token", "application/x-www-form-urlencoded",
+		strings.NewReader("grant_type=refresh_token&refresh_token=secret-refresh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	if err := rec.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixture := string(b)
+	for _, secret := range []string{"jdoe42", `"bob"`, "user:bob", "user/bob", "secret-access", "secret-refresh", "jdoe@example.com"} {
+		if strings.Contains(fixture, secret) {
+			t.Errorf("fixture leaks %q:\n%s", secret, fixture)
+		}
+	}
+	for _, want := range []string{"/v1/users/user1/playlists", `"id": "user2"`, "spotify:user:user2", "open.spotify.com/user/user2", `"name": "bobsleigh"`} {
+		if !strings.Contains(fixture, want) {
+			t.Errorf("fixture lacks %q:\n%s", want, fixture)
+		}
+	}
+
+	// replay the scrubbed exchanges
+	replayer, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hc = &http.Client{Transport: replayer}
+	resp, err = hc.Get(srv.URL + "/v1/users/user1/playlists")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `"p2"`) {
+		t.Errorf("unexpected replayed body %s", body)
+	}
+	resp, err = hc.Post(srv.URL+"/api/token", "application/x-www-form-urlencoded",
+		strings.NewReader("grant_type=refresh_token&refresh_token=other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if _, err := hc.Get(srv.URL + "/v1/users/user1/playlists"); err == nil {
+		t.Error("expected error replaying an exchange twice")
+	}
+	if unused := replayer.Unused(); len(unused) != 1 || unused[0].URL != srv.URL+"/v1/me" {
+		t.Errorf("unexpected unused requests %v", unused)
+	}
+}
@@ -0,0 +1,234 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// redacted replaces secrets in fixtures.
+const redacted = "REDACTED"
+
+// secretFields are JSON keys and form fields whose values are credentials or
+// personal data.
+var secretFields = map[string]bool{
+	"access_token":  true,
+	"refresh_token": true,
+	"code":          true,
+	"code_verifier": true,
+	"client_secret": true,
+	"email":         true,
+	"birthdate":     true,
+	"display_name":  true,
+}
+
+// scrub redacts secrets and replaces the IDs of every user appearing in a
+// response with placeholders. IDs are only replaced in request URLs and in
+// the id, uri and href fields of JSON bodies, and in external URLs, so names
+// and other text happening to contain an ID are left alone.
+func scrub(interactions []Interaction) (*Fixture, error) {
+	// collect user IDs in order of appearance so placeholders are stable
+	var users []string
+	seen := make(map[string]bool)
+	bodies := make([]interface{}, len(interactions))
+	for i := range interactions {
+		in := &interactions[i]
+		in.Request.Body = redactForm(in.Request.Body)
+		if len(in.Response.Body) == 0 {
+			continue
+		}
+		v, err := decode(in.Response.Body)
+		if err != nil {
+			return nil, err
+		}
+		collectUsers(v, func(id string) {
+			if !seen[id] {
+				seen[id] = true
+				users = append(users, id)
+			}
+		})
+		bodies[i] = redactJSON(v)
+	}
+	placeholders := make(map[string]string)
+	for i, id := range users {
+		placeholders[id] = "user" + strconv.Itoa(i+1)
+	}
+
+	for i := range interactions {
+		in := &interactions[i]
+		in.Request.URL = scrubURL(in.Request.URL, placeholders)
+		if strings.HasPrefix(in.Request.Body, "{") {
+			if v, err := decode([]byte(in.Request.Body)); err == nil {
+				b, err := encode(scrubFields(v, placeholders))
+				if err != nil {
+					return nil, err
+				}
+				in.Request.Body = string(b)
+			}
+		}
+		if bodies[i] != nil {
+			var err error
+			if in.Response.Body, err = encode(scrubFields(bodies[i], placeholders)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &Fixture{Interactions: interactions}, nil
+}
+
+// scrubFields replaces user IDs in the id, uri and href fields of v and in
+// its external URLs.
+func scrubFields(v interface{}, placeholders map[string]string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			s, ok := child.(string)
+			switch {
+			case ok && k == "id":
+				if p, ok := placeholders[s]; ok {
+					v[k] = p
+				}
+			case ok && k == "uri":
+				v[k] = scrubURI(s, placeholders)
+			case ok && k == "href":
+				v[k] = scrubURL(s, placeholders)
+			case k == "external_urls":
+				if urls, ok := child.(map[string]interface{}); ok {
+					for name, u := range urls {
+						if s, ok := u.(string); ok {
+							urls[name] = scrubURL(s, placeholders)
+						}
+					}
+				}
+			default:
+				v[k] = scrubFields(child, placeholders)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = scrubFields(child, placeholders)
+		}
+	}
+	return v
+}
+
+// scrubURI replaces the user ID in a Spotify URI such as
+// spotify:user:ID:playlist:PLAYLIST.
+func scrubURI(uri string, placeholders map[string]string) string {
+	parts := strings.Split(uri, ":")
+	for i := 1; i < len(parts); i++ {
+		if p, ok := placeholders[parts[i]]; ok && parts[i-1] == "user" {
+			parts[i] = p
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+// scrubURL replaces user IDs in the path of a Web API or web player URL,
+// such as .../users/ID/playlists or open.spotify.com/user/ID.
+func scrubURL(s string, placeholders map[string]string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Path == "" {
+		return s
+	}
+	segments := strings.Split(u.Path, "/")
+	changed := false
+	for i := 1; i < len(segments); i++ {
+		prev := segments[i-1]
+		if p, ok := placeholders[segments[i]]; ok && (prev == "users" || prev == "user") {
+			segments[i] = p
+			changed = true
+		}
+	}
+	if !changed {
+		return s
+	}
+	u.Path, u.RawPath = strings.Join(segments, "/"), ""
+	return u.String()
+}
+
+// collectUsers calls fn with the ID of every user object in v.
+func collectUsers(v interface{}, fn func(id string)) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		typ, _ := v["type"].(string)
+		uri, _ := v["uri"].(string)
+		if typ == "user" || strings.HasPrefix(uri, "spotify:user:") {
+			if id, ok := v["id"].(string); ok && id != "" {
+				fn(id)
+			}
+		}
+		// walk keys in order so placeholders don't depend on map order
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			collectUsers(v[k], fn)
+		}
+	case []interface{}:
+		for _, child := range v {
+			collectUsers(child, fn)
+		}
+	}
+}
+
+func redactJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if _, ok := child.(string); ok && secretFields[k] {
+				v[k] = redacted
+				continue
+			}
+			v[k] = redactJSON(child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redactJSON(child)
+		}
+	}
+	return v
+}
+
+// redactForm redacts credentials in a form encoded request body, such as the
+// ones sent to the token endpoint. Other bodies are returned unchanged.
+func redactForm(body string) string {
+	values, err := url.ParseQuery(body)
+	if err != nil || strings.HasPrefix(body, "{") {
+		return body
+	}
+	changed := false
+	for k := range values {
+		if secretFields[k] {
+			values.Set(k, redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return body
+	}
+	return values.Encode()
+}
+
+func decode(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	err := dec.Decode(&v)
+	return v, err
+}
+
+func encode(v interface{}) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
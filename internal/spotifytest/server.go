@@ -0,0 +1,815 @@
+// Package spotifytest provides an in-process fake of the parts of the Spotify
+// Web API used by spotifycli, for tests that must run offline.
+//
+// The fake is seeded with users, tracks and playlists, then serves them the
+// way the Web API does, including paging:
+//
+//	srv := spotifytest.NewServer()
+//	defer srv.Close()
+//	srv.AddUser(spotify.PrivateUser{User: spotify.User{ID: "alice"}})
+//	srv.AddTrack(spotify.FullTrack{SimpleTrack: spotify.SimpleTrack{ID: "t1", Name: "Song"}})
+//	id := srv.AddPlaylist(spotify.SimplePlaylist{Name: "Mix"}, "t1")
+package spotifytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zmb3/spotify"
+)
+
+// Server is a fake Spotify Web API backed by in-memory state. It is safe for
+// concurrent use.
+type Server struct {
+	// PageSize, when positive, caps the page size of every paged endpoint
+	// so tests can exercise paging with small data sets.
+	PageSize int
+
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	me        string
+	users     map[string]spotify.PrivateUser
+	tracks    map[spotify.ID]spotify.FullTrack
+	albums    map[spotify.ID]spotify.FullAlbum
+	features  map[spotify.ID]spotify.AudioFeatures
+	artists   []spotify.FullArtist
+	playlists []*playlist
+	// others are playlists outside the current user's library
+	others  []*playlist
+	playing spotify.ID
+	nextID  int
+	added   int
+}
+
+// playlist is a playlist and its tracks in order.
+type playlist struct {
+	spotify.SimplePlaylist
+	tracks []spotify.PlaylistTrack
+}
+
+// NewServer starts a fake Web API. Callers should Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		users:    make(map[string]spotify.PrivateUser),
+		tracks:   make(map[spotify.ID]spotify.FullTrack),
+		albums:   make(map[spotify.ID]spotify.FullAlbum),
+		features: make(map[spotify.ID]spotify.AudioFeatures),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// URL returns the base URL of the Web API, the equivalent of
+// https://api.spotify.com/v1/.
+func (s *Server) URL() string {
+	return s.srv.URL + "/v1/"
+}
+
+// Client returns an HTTP client configured for the server.
+func (s *Server) Client() *http.Client {
+	return s.srv.Client()
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// AddUser adds a user. The first user added becomes the current user.
+func (s *Server) AddUser(u spotify.PrivateUser) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u.URI == "" {
+		u.URI = spotify.URI("spotify:user:" + u.ID)
+	}
+	s.users[u.ID] = u
+	if s.me == "" {
+		s.me = u.ID
+	}
+}
+
+// SetCurrentUser changes the user requests are authenticated as.
+func (s *Server) SetCurrentUser(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.me = id
+}
+
+// AddTrack adds a track to the catalog.
+func (s *Server) AddTrack(t spotify.FullTrack) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t.URI == "" {
+		t.URI = spotify.URI("spotify:track:" + string(t.ID))
+	}
+	s.tracks[t.ID] = t
+}
+
+// AddAlbum adds the full details of an album, such as its release date, to
+// the catalog. Tracks refer to albums by ID.
+func (s *Server) AddAlbum(a spotify.FullAlbum) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.albums[a.ID] = a
+}
+
+// AddAudioFeatures adds the audio features of a catalog track, by its ID.
+func (s *Server) AddAudioFeatures(f spotify.AudioFeatures) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.features[f.ID] = f
+}
+
+// AddArtist adds an artist to the catalog.
+func (s *Server) AddArtist(a spotify.FullArtist) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.artists = append(s.artists, a)
+}
+
+// AddPlaylist adds a playlist containing the given catalog tracks to the
+// current user's library and returns its ID. Missing IDs and owners are
+// filled in.
+func (s *Server) AddPlaylist(p spotify.SimplePlaylist, trackIDs ...spotify.ID) spotify.ID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pl := s.newPlaylist(p, trackIDs)
+	s.playlists = append(s.playlists, pl)
+	return pl.ID
+}
+
+// AddOtherPlaylist is like AddPlaylist but leaves the playlist out of the
+// current user's library, like one of another user's playlists reached by a
+// link.
+func (s *Server) AddOtherPlaylist(p spotify.SimplePlaylist, trackIDs ...spotify.ID) spotify.ID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pl := s.newPlaylist(p, trackIDs)
+	s.others = append(s.others, pl)
+	return pl.ID
+}
+
+func (s *Server) newPlaylist(p spotify.SimplePlaylist, trackIDs []spotify.ID) *playlist {
+	if p.ID == "" {
+		p.ID = spotify.ID(s.newID("pl"))
+	}
+	if p.Owner.ID == "" {
+		p.Owner = s.users[s.me].User
+	}
+	p.URI = spotify.URI("spotify:playlist:" + string(p.ID))
+	p.SnapshotID = s.newID("snap")
+	pl := &playlist{SimplePlaylist: p}
+	for _, id := range trackIDs {
+		pl.tracks = append(pl.tracks, s.playlistTrack(id))
+	}
+	return pl
+}
+
+// SetPlaying sets the currently playing track. An empty ID stops playback.
+func (s *Server) SetPlaying(id spotify.ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.playing = id
+}
+
+// Playlists returns the playlists in the current user's library.
+func (s *Server) Playlists() []spotify.SimplePlaylist {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	playlists := make([]spotify.SimplePlaylist, len(s.playlists))
+	for i, p := range s.playlists {
+		playlists[i] = p.simple()
+	}
+	return playlists
+}
+
+// PlaylistTracks returns the IDs of the tracks in a playlist, in order.
+func (s *Server) PlaylistTracks(id spotify.ID) []spotify.ID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.playlist(id)
+	if p == nil {
+		return nil
+	}
+	ids := make([]spotify.ID, len(p.tracks))
+	for i, t := range p.tracks {
+		ids[i] = t.Track.ID
+	}
+	return ids
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1"), "/"), "/")
+	switch {
+	case match(r, "GET", path, "me"):
+		s.getMe(w, r)
+	case match(r, "GET", path, "me", "playlists"):
+		s.getMyPlaylists(w, r)
+	case match(r, "GET", path, "me", "player", "currently-playing"):
+		s.getCurrentlyPlaying(w, r)
+	case match(r, "GET", path, "search"):
+		s.search(w, r)
+	case match(r, "GET", path, "albums"):
+		s.getAlbums(w, r)
+	case match(r, "GET", path, "audio-features"):
+		s.getAudioFeatures(w, r)
+	case match(r, "GET", path, "tracks"):
+		s.getTracks(w, r)
+	case match(r, "GET", path, "tracks", "*"):
+		s.getTrack(w, r, spotify.ID(path[1]))
+	case match(r, "POST", path, "users", "*", "playlists"):
+		s.createPlaylist(w, r, path[1])
+	case match(r, "GET", path, "playlists", "*"):
+		s.getPlaylist(w, r, spotify.ID(path[1]))
+	case match(r, "DELETE", path, "playlists", "*", "followers"):
+		s.unfollowPlaylist(w, r, spotify.ID(path[1]))
+	case match(r, "GET", path, "playlists", "*", "tracks"):
+		s.getPlaylistTracks(w, r, spotify.ID(path[1]))
+	case match(r, "POST", path, "playlists", "*", "tracks"):
+		s.addPlaylistTracks(w, r, spotify.ID(path[1]))
+	case match(r, "PUT", path, "playlists", "*", "tracks"):
+		s.putPlaylistTracks(w, r, spotify.ID(path[1]))
+	case match(r, "DELETE", path, "playlists", "*", "tracks"):
+		s.removePlaylistTracks(w, r, spotify.ID(path[1]))
+	default:
+		writeError(w, http.StatusNotFound, "Service not found")
+	}
+}
+
+func (s *Server) getMe(w http.ResponseWriter, r *http.Request) {
+	u, ok := s.users[s.me]
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "No token provided")
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+func (s *Server) getMyPlaylists(w http.ResponseWriter, r *http.Request) {
+	offset, limit := s.paging(r, 20, 50)
+	var page spotify.SimplePlaylistPage
+	page.Playlists = []spotify.SimplePlaylist{}
+	for i := offset; i < len(s.playlists) && i < offset+limit; i++ {
+		page.Playlists = append(page.Playlists, s.playlists[i].simple())
+	}
+	page.Endpoint, page.Next = pageLinks(r, offset, limit, len(s.playlists))
+	page.Limit, page.Offset, page.Total = limit, offset, len(s.playlists)
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) getCurrentlyPlaying(w http.ResponseWriter, r *http.Request) {
+	// the Web API answers with an empty body when nothing is playing
+	t, ok := s.tracks[s.playing]
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, spotify.CurrentlyPlaying{Playing: true, Item: &t})
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	query, filters := parseQuery(r.URL.Query().Get("q"))
+	offset, limit := s.paging(r, 20, 50)
+
+	var result spotify.SearchResult
+	for _, typ := range strings.Split(r.URL.Query().Get("type"), ",") {
+		switch typ {
+		case "track":
+			var matches []spotify.FullTrack
+			for _, t := range s.catalog() {
+				if strings.Contains(strings.ToLower(t.Name), query) &&
+					strings.Contains(strings.ToLower(t.Name), filters["track"]) &&
+					anyArtistContains(t.Artists, filters["artist"]) {
+					matches = append(matches, t)
+				}
+			}
+			var page spotify.FullTrackPage
+			page.Tracks = matches[min(offset, len(matches)):min(offset+limit, len(matches))]
+			page.Endpoint, page.Next = pageLinks(r, offset, limit, len(matches))
+			page.Limit, page.Offset, page.Total = limit, offset, len(matches)
+			result.Tracks = &page
+		case "album":
+			var matches []spotify.SimpleAlbum
+			seen := make(map[spotify.ID]bool)
+			for _, t := range s.catalog() {
+				if !seen[t.Album.ID] && strings.Contains(strings.ToLower(t.Album.Name), query) {
+					seen[t.Album.ID] = true
+					matches = append(matches, t.Album)
+				}
+			}
+			var page spotify.SimpleAlbumPage
+			page.Albums = matches[min(offset, len(matches)):min(offset+limit, len(matches))]
+			page.Endpoint, page.Next = pageLinks(r, offset, limit, len(matches))
+			page.Limit, page.Offset, page.Total = limit, offset, len(matches)
+			result.Albums = &page
+		case "artist":
+			var matches []spotify.FullArtist
+			for _, a := range s.artists {
+				if strings.Contains(strings.ToLower(a.Name), query) {
+					matches = append(matches, a)
+				}
+			}
+			var page spotify.FullArtistPage
+			page.Artists = matches[min(offset, len(matches)):min(offset+limit, len(matches))]
+			page.Endpoint, page.Next = pageLinks(r, offset, limit, len(matches))
+			page.Limit, page.Offset, page.Total = limit, offset, len(matches)
+			result.Artists = &page
+		case "playlist":
+			var matches []spotify.SimplePlaylist
+			for _, p := range s.playlists {
+				if strings.Contains(strings.ToLower(p.Name), query) {
+					matches = append(matches, p.simple())
+				}
+			}
+			var page spotify.SimplePlaylistPage
+			page.Playlists = matches[min(offset, len(matches)):min(offset+limit, len(matches))]
+			page.Endpoint, page.Next = pageLinks(r, offset, limit, len(matches))
+			page.Limit, page.Offset, page.Total = limit, offset, len(matches)
+			result.Playlists = &page
+		default:
+			writeError(w, http.StatusBadRequest, "Bad search type field "+typ)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// parseQuery splits a search query into its field filters, such as
+// track:"Some Title" or artist:name, and the remaining text, all lowercased.
+func parseQuery(q string) (string, map[string]string) {
+	filters := make(map[string]string)
+	var text []string
+	q = strings.ToLower(q)
+	for q = strings.TrimSpace(q); q != ""; q = strings.TrimSpace(q) {
+		// a field filter, its value quoted or up to the next space
+		if i := strings.Index(q, ":"); i > 0 && !strings.ContainsAny(q[:i], " \"") {
+			field, rest := q[:i], q[i+1:]
+			var value string
+			if strings.HasPrefix(rest, "\"") {
+				end := strings.Index(rest[1:], "\"")
+				if end < 0 {
+					end = len(rest) - 1
+				}
+				value, q = rest[1:end+1], rest[min(end+2, len(rest)):]
+			} else {
+				end := strings.Index(rest, " ")
+				if end < 0 {
+					end = len(rest)
+				}
+				value, q = rest[:end], rest[end:]
+			}
+			filters[field] = value
+			continue
+		}
+		end := strings.Index(q, " ")
+		if end < 0 {
+			end = len(q)
+		}
+		text = append(text, q[:end])
+		q = q[end:]
+	}
+	return strings.Join(text, " "), filters
+}
+
+func anyArtistContains(artists []spotify.SimpleArtist, name string) bool {
+	if name == "" {
+		return true
+	}
+	for _, a := range artists {
+		if strings.Contains(strings.ToLower(a.Name), name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) getTracks(w http.ResponseWriter, r *http.Request) {
+	ids := strings.Split(r.URL.Query().Get("ids"), ",")
+	if len(ids) > 50 {
+		writeError(w, http.StatusBadRequest, "Too many ids requested")
+		return
+	}
+	// unknown tracks come back as null
+	tracks := make([]*spotify.FullTrack, len(ids))
+	for i, id := range ids {
+		if t, ok := s.tracks[spotify.ID(id)]; ok {
+			tracks[i] = &t
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tracks": tracks})
+}
+
+func (s *Server) getTrack(w http.ResponseWriter, r *http.Request, id spotify.ID) {
+	t, ok := s.tracks[id]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (s *Server) getAudioFeatures(w http.ResponseWriter, r *http.Request) {
+	ids := strings.Split(r.URL.Query().Get("ids"), ",")
+	if len(ids) > 100 {
+		writeError(w, http.StatusBadRequest, "Too many ids requested")
+		return
+	}
+	features := make([]*spotify.AudioFeatures, len(ids))
+	for i, id := range ids {
+		if f, ok := s.features[spotify.ID(id)]; ok {
+			features[i] = &f
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"audio_features": features})
+}
+
+func (s *Server) getAlbums(w http.ResponseWriter, r *http.Request) {
+	ids := strings.Split(r.URL.Query().Get("ids"), ",")
+	if len(ids) > 20 {
+		writeError(w, http.StatusBadRequest, "Too many ids requested")
+		return
+	}
+	// unknown albums come back as null
+	albums := make([]*spotify.FullAlbum, len(ids))
+	for i, id := range ids {
+		if a, ok := s.albums[spotify.ID(id)]; ok {
+			albums[i] = &a
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"albums": albums})
+}
+
+func (s *Server) createPlaylist(w http.ResponseWriter, r *http.Request, userID string) {
+	if userID != s.me {
+		writeError(w, http.StatusForbidden, "You cannot create a playlist for another user")
+		return
+	}
+	var body struct {
+		Name   string `json:"name"`
+		Public bool   `json:"public"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Error parsing JSON.")
+		return
+	}
+
+	p := &playlist{SimplePlaylist: spotify.SimplePlaylist{
+		ID:         spotify.ID(s.newID("pl")),
+		Name:       body.Name,
+		IsPublic:   body.Public,
+		Owner:      s.users[s.me].User,
+		SnapshotID: s.newID("snap"),
+	}}
+	p.URI = spotify.URI("spotify:playlist:" + string(p.ID))
+	s.playlists = append(s.playlists, p)
+	writeJSON(w, http.StatusCreated, spotify.FullPlaylist{SimplePlaylist: p.simple()})
+}
+
+func (s *Server) unfollowPlaylist(w http.ResponseWriter, r *http.Request, id spotify.ID) {
+	for i, p := range s.playlists {
+		if p.ID == id {
+			s.playlists = append(s.playlists[:i], s.playlists[i+1:]...)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "Not found.")
+}
+
+func (s *Server) getPlaylist(w http.ResponseWriter, r *http.Request, id spotify.ID) {
+	if len(id) != 22 {
+		writeError(w, http.StatusBadRequest, "Invalid playlist Id")
+		return
+	}
+	p := s.playlist(id)
+	if p == nil {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	writeJSON(w, http.StatusOK, p.simple())
+}
+
+func (s *Server) getPlaylistTracks(w http.ResponseWriter, r *http.Request, id spotify.ID) {
+	p := s.playlist(id)
+	if p == nil {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	offset, limit := s.paging(r, 100, 100)
+	var page spotify.PlaylistTrackPage
+	page.Tracks = p.tracks[min(offset, len(p.tracks)):min(offset+limit, len(p.tracks))]
+	page.Endpoint, page.Next = pageLinks(r, offset, limit, len(p.tracks))
+	page.Limit, page.Offset, page.Total = limit, offset, len(p.tracks)
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) addPlaylistTracks(w http.ResponseWriter, r *http.Request, id spotify.ID) {
+	p := s.writablePlaylist(w, id)
+	if p == nil {
+		return
+	}
+	var body struct {
+		URIs []string `json:"uris"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Error parsing JSON.")
+		return
+	}
+	if len(body.URIs) > 100 {
+		writeError(w, http.StatusBadRequest, "You can add a maximum of 100 tracks per request.")
+		return
+	}
+
+	var added []spotify.PlaylistTrack
+	for _, uri := range body.URIs {
+		id := spotify.ID(strings.TrimPrefix(uri, "spotify:track:"))
+		if _, ok := s.tracks[id]; !ok {
+			writeError(w, http.StatusBadRequest, "Invalid track uri: "+uri)
+			return
+		}
+		added = append(added, s.playlistTrack(id))
+	}
+	p.tracks = append(p.tracks, added...)
+	p.SnapshotID = s.newID("snap")
+	writeJSON(w, http.StatusCreated, map[string]string{"snapshot_id": p.SnapshotID})
+}
+
+// putPlaylistTracks replaces the tracks of a playlist with the uris query
+// parameter, or reorders them as the body says.
+func (s *Server) putPlaylistTracks(w http.ResponseWriter, r *http.Request, id spotify.ID) {
+	p := s.writablePlaylist(w, id)
+	if p == nil {
+		return
+	}
+
+	if uris := r.URL.Query().Get("uris"); uris != "" {
+		var tracks []spotify.PlaylistTrack
+		for _, uri := range strings.Split(uris, ",") {
+			id := spotify.ID(strings.TrimPrefix(uri, "spotify:track:"))
+			if _, ok := s.tracks[id]; !ok {
+				writeError(w, http.StatusBadRequest, "Invalid track uri: "+uri)
+				return
+			}
+			tracks = append(tracks, s.playlistTrack(id))
+		}
+		if len(tracks) > 100 {
+			writeError(w, http.StatusBadRequest, "You can set a maximum of 100 tracks per request.")
+			return
+		}
+		p.tracks = tracks
+		p.SnapshotID = s.newID("snap")
+		writeJSON(w, http.StatusCreated, map[string]string{"snapshot_id": p.SnapshotID})
+		return
+	}
+
+	var body struct {
+		RangeStart   *int   `json:"range_start"`
+		RangeLength  int    `json:"range_length"`
+		InsertBefore *int   `json:"insert_before"`
+		SnapshotID   string `json:"snapshot_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Error parsing JSON.")
+		return
+	}
+	if body.SnapshotID != "" && body.SnapshotID != p.SnapshotID {
+		writeError(w, http.StatusBadRequest, "Invalid snapshot id.")
+		return
+	}
+	if body.RangeLength == 0 {
+		body.RangeLength = 1
+	}
+	if body.RangeStart == nil || body.InsertBefore == nil {
+		writeError(w, http.StatusBadRequest, "Missing range_start or insert_before.")
+		return
+	}
+	start, before, n := *body.RangeStart, *body.InsertBefore, body.RangeLength
+	if start < 0 || n < 1 || start+n > len(p.tracks) || before < 0 || before > len(p.tracks) {
+		writeError(w, http.StatusBadRequest, "Index out of bounds.")
+		return
+	}
+
+	// take the range out, then insert it before the track that was at
+	// insert_before
+	moved := append([]spotify.PlaylistTrack(nil), p.tracks[start:start+n]...)
+	rest := append(append([]spotify.PlaylistTrack(nil), p.tracks[:start]...), p.tracks[start+n:]...)
+	if before > start {
+		before = max(before-n, start)
+	}
+	p.tracks = append(append(append([]spotify.PlaylistTrack(nil), rest[:before]...), moved...), rest[before:]...)
+	p.SnapshotID = s.newID("snap")
+	writeJSON(w, http.StatusOK, map[string]string{"snapshot_id": p.SnapshotID})
+}
+
+func (s *Server) removePlaylistTracks(w http.ResponseWriter, r *http.Request, id spotify.ID) {
+	p := s.writablePlaylist(w, id)
+	if p == nil {
+		return
+	}
+	var body struct {
+		Tracks []struct {
+			URI       string `json:"uri"`
+			Positions []int  `json:"positions"`
+		} `json:"tracks"`
+		SnapshotID string `json:"snapshot_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Error parsing JSON.")
+		return
+	}
+	// the fake keeps no history, so positions only apply to the current
+	// snapshot
+	if body.SnapshotID != "" && body.SnapshotID != p.SnapshotID {
+		writeError(w, http.StatusBadRequest, "Invalid snapshot id.")
+		return
+	}
+	if len(body.Tracks) > 100 {
+		writeError(w, http.StatusBadRequest, "You can remove a maximum of 100 tracks per request.")
+		return
+	}
+
+	// mark every occurrence of a track, or only the given positions
+	remove := make(map[int]bool)
+	for _, t := range body.Tracks {
+		id := spotify.ID(strings.TrimPrefix(t.URI, "spotify:track:"))
+		if len(t.Positions) == 0 {
+			for i, pt := range p.tracks {
+				if pt.Track.ID == id {
+					remove[i] = true
+				}
+			}
+			continue
+		}
+		for _, pos := range t.Positions {
+			if pos < 0 || pos >= len(p.tracks) || p.tracks[pos].Track.ID != id {
+				writeError(w, http.StatusBadRequest, "Could not remove tracks, please check parameters.")
+				return
+			}
+			remove[pos] = true
+		}
+	}
+
+	kept := p.tracks[:0:0]
+	for i, t := range p.tracks {
+		if !remove[i] {
+			kept = append(kept, t)
+		}
+	}
+	p.tracks = kept
+	p.SnapshotID = s.newID("snap")
+	writeJSON(w, http.StatusOK, map[string]string{"snapshot_id": p.SnapshotID})
+}
+
+// writablePlaylist looks up a playlist the current user may modify, writing
+// an error response when there is none.
+func (s *Server) writablePlaylist(w http.ResponseWriter, id spotify.ID) *playlist {
+	p := s.playlist(id)
+	if p == nil {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return nil
+	}
+	if p.Owner.ID != s.me && !p.Collaborative {
+		writeError(w, http.StatusForbidden, "You cannot modify a playlist you don't own.")
+		return nil
+	}
+	return p
+}
+
+func (s *Server) playlist(id spotify.ID) *playlist {
+	for _, playlists := range [][]*playlist{s.playlists, s.others} {
+		for _, p := range playlists {
+			if p.ID == id {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) playlistTrack(id spotify.ID) spotify.PlaylistTrack {
+	// space additions a second apart so added_at orders them
+	s.added++
+	return spotify.PlaylistTrack{
+		AddedAt: fmt.Sprintf("2018-01-01T%02d:%02d:%02dZ", s.added/3600%24, s.added/60%60, s.added%60),
+		AddedBy: s.users[s.me].User,
+		Track:   s.tracks[id],
+	}
+}
+
+// catalog returns the catalog tracks in a stable order.
+func (s *Server) catalog() []spotify.FullTrack {
+	ids := make([]string, 0, len(s.tracks))
+	for id := range s.tracks {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+	tracks := make([]spotify.FullTrack, len(ids))
+	for i, id := range ids {
+		tracks[i] = s.tracks[spotify.ID(id)]
+	}
+	return tracks
+}
+
+func (s *Server) newID(prefix string) string {
+	s.nextID++
+	// as long as Spotify IDs
+	return fmt.Sprintf("%s%0*d", prefix, 22-len(prefix), s.nextID)
+}
+
+// paging reads the offset and limit query parameters, applying the
+// endpoint's default and maximum page size.
+func (s *Server) paging(r *http.Request, def, max int) (offset, limit int) {
+	limit = def
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= max {
+		limit = l
+	}
+	if s.PageSize > 0 && limit > s.PageSize {
+		limit = s.PageSize
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o > 0 {
+		offset = o
+	}
+	return offset, limit
+}
+
+func (p *playlist) simple() spotify.SimplePlaylist {
+	sp := p.SimplePlaylist
+	sp.Tracks = spotify.PlaylistTracks{Total: uint(len(p.tracks))}
+	return sp
+}
+
+// pageLinks returns the href of the current page and the link to the next
+// one, if any.
+func pageLinks(r *http.Request, offset, limit, total int) (href, next string) {
+	link := func(offset int) string {
+		u := url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path}
+		q := r.URL.Query()
+		q.Set("offset", strconv.Itoa(offset))
+		q.Set("limit", strconv.Itoa(limit))
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	href = link(offset)
+	if offset+limit < total {
+		next = link(offset + limit)
+	}
+	return href, next
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	var e struct {
+		E spotify.Error `json:"error"`
+	}
+	e.E = spotify.Error{Status: code, Message: message}
+	writeJSON(w, code, e)
+}
+
+func match(r *http.Request, method string, path []string, pattern ...string) bool {
+	if r.Method != method || len(path) != len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if p != "*" && p != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}